@@ -49,6 +49,7 @@ var directMappedInstructions = [255]string{
   0b11001100: "INT3",
   0b11001110: "INTO",
   0b11001111: "IRET",
+  0b11010110: "SALC",
   0b11010111: "XLAT",
   0b11110000: "LOCK ",
   0b11110100: "HLT",
@@ -2,6 +2,7 @@ package Disassembly
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/P100sch/Intel8086Simulator/Simulation/Shared"
 )
@@ -104,6 +105,15 @@ func disassembleRegisterOrMemory(segmentOverride string, wide byte, data []byte,
 	return second, nil
 }
 
+// disassembleSegmentPrefix converts a segment override into a prefix for instructions without register/memory parameters
+//   - segmentOverride contains the segment register override, if applicable
+func disassembleSegmentPrefix(segmentOverride string) string {
+	if segmentOverride == "" {
+		return ""
+	}
+	return strings.TrimSuffix(segmentOverride, ":") + " "
+}
+
 // readData reads a chunk of data as a number and strings it
 //   - signed if the number is signed
 //   - wide if the data is 16bits wide
@@ -32,14 +32,24 @@ func newInvalidParameterErrorInvalidInstruction(position int) *DisassembleError
 
 //endregion
 
-// Disassemble instruction stream to assembly
+// Disassemble instruction stream to assembly. Undocumented opcodes are invalid.
+// Possible errors:
+//   - invalid instruction
+//   - invalid parameters
+//   - instruction stream stops before complete decoding of instruction
+func Disassemble(data []byte) (string, error) {
+	return DisassembleWithOpcodeMode(data, Shared.STRICT_OPCODES)
+}
+
+// DisassembleWithOpcodeMode disassembles instruction stream to assembly and treats undocumented opcodes according to mode.
+// Undocumented aliases are disassembled as the documented instruction they execute.
 // Possible errors:
 //   - invalid instruction
 //   - invalid parameters
 //   - instruction stream stops before complete decoding of instruction
 //
 //goland:noinspection SpellCheckingInspection
-func Disassemble(data []byte) (string, error) {
+func DisassembleWithOpcodeMode(data []byte, mode Shared.OpcodeMode) (string, error) {
 	startOfInsctruction := -1
 	builder := strings.Builder{}
 
@@ -52,7 +62,15 @@ func Disassemble(data []byte) (string, error) {
 		var assembly = ""
 		var segmentRegister = false
 
-		switch data[position] {
+		opcode := data[position]
+		if Shared.IsUndocumentedOpcode(opcode) {
+			if mode == Shared.STRICT_OPCODES {
+				return "", &DisassembleError{Message: "undocumented instruction", Pos: position}
+			}
+			opcode = Shared.GetDocumentedOpcode(opcode)
+		}
+
+		switch opcode {
 
 		//MOV R/M to segment register
 		case 0b10001100:
@@ -105,7 +123,7 @@ func Disassemble(data []byte) (string, error) {
 			if position >= dataLength {
 				return "", newInvalidParameterErrorPrematureEndOfStream(position)
 			}
-			second := "[" + strconv.FormatUint(uint64(data[position-1])|uint64(data[position])<<8, 10) + "]"
+			second := segmentOverride + "[" + strconv.FormatUint(uint64(data[position-1])|uint64(data[position])<<8, 10) + "]"
 			builder.WriteString("MOV ")
 			builder.WriteString(order(accumulatorIsSource, first, second))
 		//MOV immediate into register
@@ -293,27 +311,27 @@ func Disassemble(data []byte) (string, error) {
 			}
 			builder.WriteString(value)
 			if wide {
-				builder.WriteString(", AL\n")
+				builder.WriteString(", AL")
 			} else {
-				builder.WriteString(", AX\n")
+				builder.WriteString(", AX")
 			}
 		//IN variable port
 		case 0b11101100:
 			fallthrough
 		case 0b11101101:
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("IN AL, DX\n")
+				builder.WriteString("IN AL, DX")
 			} else {
-				builder.WriteString("IN AX, DX\n")
+				builder.WriteString("IN AX, DX")
 			}
 		//OUT variable port
 		case 0b11101110:
 			fallthrough
 		case 0b11101111:
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("OUT DX, AL\n")
+				builder.WriteString("OUT DX, AL")
 			} else {
-				builder.WriteString("OUT DX, AX\n")
+				builder.WriteString("OUT DX, AX")
 			}
 
 		//LEA load EA to register
@@ -557,18 +575,20 @@ func Disassemble(data []byte) (string, error) {
 		case 0b11010101:
 			var name string
 			if data[position]&Shared.WideMask == 0 {
-				name = "AAM\n"
+				name = "AAM"
 			} else {
-				name = "AAD\n"
+				name = "AAD"
 			}
 			position++
 			if position == dataLength {
 				return "", newInvalidParameterErrorPrematureEndOfStream(position)
 			}
+			builder.WriteString(name)
+			//the base is only written if it differs from the documented base 10
 			if data[position] != 0b00001010 {
-				return "", newInvalidParameterError(position, "")
+				builder.WriteString(" ")
+				builder.WriteString(strconv.Itoa(int(data[position])))
 			}
-			builder.WriteString(name)
 
 		//ROL/ROR/RCL/RCR/SHL/SAL/SHR/SAR
 		case 0b11010000:
@@ -585,19 +605,17 @@ func Disassemble(data []byte) (string, error) {
 				return "", newInvalidParameterErrorPrematureEndOfStream(position)
 			}
 			reg := data[position] & Shared.RegMask >> 3
-			if reg == 0b110 {
-				return "", newInvalidParameterErrorInvalidInstruction(position)
-			}
-			var name = [8]string{"ROL ", "ROR ", "RCL ", "RCR ", "SHL ", "SHR ", "", "SAR "}[reg]
+			//0b110 is an undocumented alias of SAL
+			var name = [8]string{"ROL ", "ROR ", "RCL ", "RCR ", "SHL ", "SHR ", "SAL ", "SAR "}[reg]
 			assembly, err = disassembleStandardParameters(name, segmentOverride, true, false, false, false, false, wide, data, &position)
 			if err != nil {
 				return "", err
 			}
 			builder.WriteString(assembly)
 			if countInCL {
-				builder.WriteString(", CL\n")
+				builder.WriteString(", CL")
 			} else {
-				builder.WriteString(", 1\n")
+				builder.WriteString(", 1")
 			}
 
 		//AND register with R/M
@@ -719,55 +737,62 @@ func Disassemble(data []byte) (string, error) {
 		case 0b11110010:
 			fallthrough
 		case 0b11110011:
+			//prefixes are printed in the order they appear
+			builder.WriteString(disassembleSegmentPrefix(segmentOverride))
+			segmentOverride = ""
 			if data[position]&Shared.WideMask == 0 {
 				builder.WriteString("REPNZ ")
 			} else {
 				builder.WriteString("REPZ ")
 			}
+			continue
 		//MOVS
 		case 0b10100100:
 			fallthrough
 		case 0b10100101:
+			builder.WriteString(disassembleSegmentPrefix(segmentOverride))
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("MOVSB\n")
+				builder.WriteString("MOVSB")
 			} else {
-				builder.WriteString("MOVSW\n")
+				builder.WriteString("MOVSW")
 			}
 		//CMPS
 		case 0b10100110:
 			fallthrough
 		case 0b10100111:
+			builder.WriteString(disassembleSegmentPrefix(segmentOverride))
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("CMPSB\n")
+				builder.WriteString("CMPSB")
 			} else {
-				builder.WriteString("CMPSW\n")
+				builder.WriteString("CMPSW")
 			}
 		//SCAS
 		case 0b10101110:
 			fallthrough
 		case 0b10101111:
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("SCASB\n")
+				builder.WriteString("SCASB")
 			} else {
-				builder.WriteString("SCASW\n")
+				builder.WriteString("SCASW")
 			}
 		//LODS
 		case 0b10101100:
 			fallthrough
 		case 0b10101101:
+			builder.WriteString(disassembleSegmentPrefix(segmentOverride))
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("LODSB\n")
+				builder.WriteString("LODSB")
 			} else {
-				builder.WriteString("LODSW\n")
+				builder.WriteString("LODSW")
 			}
 		//STOS
 		case 0b10101010:
 			fallthrough
 		case 0b10101011:
 			if data[position]&Shared.WideMask == 0 {
-				builder.WriteString("STOSB\n")
+				builder.WriteString("STOSB")
 			} else {
-				builder.WriteString("STOSW\n")
+				builder.WriteString("STOSW")
 			}
 
 		//RET intra segment with immediate
@@ -781,7 +806,7 @@ func Disassemble(data []byte) (string, error) {
 			fallthrough
 		//JMP direct intra segment
 		case 0b11101001:
-			instr := data[position] & 0b00001111
+			instr := opcode & 0b00001111
 			var name = []string{0b0010: "RET ", 0b1000: "CALL ", 0b1001: "JMP ", 0b1010: "RETF "}[instr]
 			position += 2
 			if position >= dataLength {
@@ -935,7 +960,7 @@ func Disassemble(data []byte) (string, error) {
 				0b11100010: "LOOP ",
 				0b11100011: "JCXZ ",
 				0b11101011: "JMP ",
-			}[data[position]]
+			}[opcode]
 			assembly, err = readAndDisassembleImmediateIntraSegmentJump(name, false, data, &position)
 			if err != nil {
 				return "", err
@@ -1067,8 +1092,15 @@ func Disassemble(data []byte) (string, error) {
 			fallthrough
 		//STD
 		case 0b11111101:
-			builder.WriteString(directMappedInstructions[data[position]])
-			if data[position] == 0b11110000 {
+			fallthrough
+		//SALC
+		case 0b11010110:
+			if opcode == 0b11010111 || opcode == 0b11110000 {
+				builder.WriteString(disassembleSegmentPrefix(segmentOverride))
+			}
+			builder.WriteString(directMappedInstructions[opcode])
+			if opcode == 0b11110000 {
+				segmentOverride = ""
 				continue
 			}
 
@@ -0,0 +1,48 @@
+package Shared
+
+// OpcodeMode defines how opcodes are treated that only exist on the 8086 silicon and not in its documentation
+type OpcodeMode byte
+
+const (
+  // STRICT_OPCODES reports undocumented opcodes as invalid instructions
+  STRICT_OPCODES OpcodeMode = iota
+  // SILICON_OPCODES decodes and executes undocumented opcodes like the 8086 does
+  SILICON_OPCODES
+)
+
+// IsUndocumentedOpcode checks if the 8086 executes opcode although it is not documented
+func IsUndocumentedOpcode(opcode byte) bool {
+  switch opcode {
+  //POP CS
+  case 0b00001111:
+    return true
+  //RET aliases
+  case 0b11000000, 0b11000001, 0b11001000, 0b11001001:
+    return true
+  //SALC
+  case 0b11010110:
+    return true
+  //LOCK alias
+  case 0b11110001:
+    return true
+  }
+  //conditional jump aliases
+  return opcode&0b11110000 == 0b01100000
+}
+
+// GetDocumentedOpcode gets the documented opcode the 8086 executes for an undocumented alias or opcode if it is no alias
+func GetDocumentedOpcode(opcode byte) byte {
+  switch opcode {
+  //RET aliases
+  case 0b11000000, 0b11000001, 0b11001000, 0b11001001:
+    return opcode | 0b00000010
+  //LOCK alias
+  case 0b11110001:
+    return 0b11110000
+  }
+  //conditional jump aliases
+  if opcode&0b11110000 == 0b01100000 {
+    return opcode | 0b00010000
+  }
+  return opcode
+}
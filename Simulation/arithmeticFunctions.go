@@ -1,63 +1,348 @@
 package Simulation
 
-func addAndUpdateFlags(addend1, addend2 uint16, wide bool) uint16 {
-	var maxValue uint32
-	var signBit uint16
-	if wide {
-		maxValue = uint32(_W_MAX)
-		signBit = _W_SIGN
+func (cpu *CPU) addAndUpdateFlags(addend1, addend2 uint16, wide bool) uint16 {
+	return cpu.addWithCarryAndUpdateFlags(addend1, addend2, 0, wide)
+}
+
+// addWithCarryAndUpdateFlags adds both addends and the carry, which can be 0 or 1
+func (cpu *CPU) addWithCarryAndUpdateFlags(addend1, addend2 uint16, carry byte, wide bool) uint16 {
+	maxValue, signBit := getMaxValueAndSignBit(wide)
+	addend1 &= maxValue
+	addend2 &= maxValue
+	sum := uint32(addend1) + uint32(addend2) + uint32(carry)
+	truncatedSum := uint16(sum) & maxValue
+
+	if sum > uint32(maxValue) {
+		cpu.CF = 1
 	} else {
-		maxValue = uint32(_B_MAX)
-		signBit = _B_SIGN
+		cpu.CF = 0
 	}
-	sum := uint32(addend1)&maxValue + uint32(addend2)&maxValue
-	truncatedSum := uint16(sum & maxValue)
+	if (addend1&0b1111)+(addend2&0b1111)+uint16(carry) > 0b1111 {
+		cpu.AF = 1
+	} else {
+		cpu.AF = 0
+	}
+	//overflow if both addends have the same sign and the sum has a different one
+	if (addend1^truncatedSum)&(addend2^truncatedSum)&signBit != 0 {
+		cpu.OF = 1
+	} else {
+		cpu.OF = 0
+	}
+	cpu.setCommonFlags(truncatedSum, signBit)
+	return truncatedSum
+}
+
+func (cpu *CPU) subAndUpateFlags(minuend, subtrahend uint16, wide bool) uint16 {
+	return cpu.subWithBorrowAndUpdateFlags(minuend, subtrahend, 0, wide)
+}
+
+// subWithBorrowAndUpdateFlags subtracts the subtrahend and the borrow, which can be 0 or 1, from the minuend
+func (cpu *CPU) subWithBorrowAndUpdateFlags(minuend, subtrahend uint16, borrow byte, wide bool) uint16 {
+	maxValue, signBit := getMaxValueAndSignBit(wide)
+	minuend &= maxValue
+	subtrahend &= maxValue
+	difference := (minuend - subtrahend - uint16(borrow)) & maxValue
 
-	if sum > maxValue {
-		CF = 1
+	if uint32(minuend) < uint32(subtrahend)+uint32(borrow) {
+		cpu.CF = 1
 	} else {
-		CF = 0
+		cpu.CF = 0
 	}
-	if (addend1&0b1111)+(addend2&0b1111) > 0b1111 {
-		AF = 1
+	if minuend&0b1111 < subtrahend&0b1111+uint16(borrow) {
+		cpu.AF = 1
 	} else {
-		AF = 0
+		cpu.AF = 0
 	}
+	//overflow if the operands have different signs and the difference has a different sign than the minuend
+	if (minuend^subtrahend)&(minuend^difference)&signBit != 0 {
+		cpu.OF = 1
+	} else {
+		cpu.OF = 0
+	}
+	cpu.setCommonFlags(difference, signBit)
+	return difference
+}
 
-	sign1 := addend1 & signBit
-	sign2 := addend2 & signBit
-	signSum := truncatedSum & signBit
-	if sign1&sign2 == signSum || sign1|sign2 == signSum {
-		OF = 0
+// incAndUpdateFlags adds 1 to value without changing CF
+func (cpu *CPU) incAndUpdateFlags(value uint16, wide bool) uint16 {
+	carry := cpu.CF
+	result := cpu.addAndUpdateFlags(value, 1, wide)
+	cpu.CF = carry
+	return result
+}
+
+// decAndUpdateFlags subtracts 1 from value without changing CF
+func (cpu *CPU) decAndUpdateFlags(value uint16, wide bool) uint16 {
+	carry := cpu.CF
+	result := cpu.subAndUpateFlags(value, 1, wide)
+	cpu.CF = carry
+	return result
+}
+
+// multiplyAndUpdateFlags multiplies AL or AX with the multiplier and stores the product in AX or DX:AX.
+// CF and OF are set if the upper half of the product is significant. SF, ZF, AF and PF are undefined.
+func (cpu *CPU) multiplyAndUpdateFlags(multiplier uint16, signed, wide bool) {
+	var upperHalfSignificant bool
+	switch {
+	case !wide && !signed:
+		cpu.AX = (cpu.AX & _L) * (multiplier & _L)
+		upperHalfSignificant = readH(cpu.AX) != 0
+	case !wide && signed:
+		product := int16(int8(cpu.AX)) * int16(int8(multiplier))
+		cpu.AX = uint16(product)
+		upperHalfSignificant = product != int16(int8(product))
+	case wide && !signed:
+		product := uint32(cpu.AX) * uint32(multiplier)
+		cpu.AX = uint16(product)
+		cpu.DX = uint16(product >> 16)
+		upperHalfSignificant = cpu.DX != 0
+	case wide && signed:
+		product := int32(int16(cpu.AX)) * int32(int16(multiplier))
+		cpu.AX = uint16(product)
+		cpu.DX = uint16(product >> 16)
+		upperHalfSignificant = product != int32(int16(product))
+	}
+	if upperHalfSignificant {
+		cpu.CF = 1
+		cpu.OF = 1
 	} else {
-		OF = 1
+		cpu.CF = 0
+		cpu.OF = 0
 	}
-	setCommonFlags(truncatedSum, signBit)
-	return truncatedSum
+	cpu.setUndefinedFlag(&cpu.SF)
+	cpu.setUndefinedFlag(&cpu.ZF)
+	cpu.setUndefinedFlag(&cpu.AF)
+	cpu.setUndefinedFlag(&cpu.PF)
 }
 
-func subAndUpateFlags(minuend, subtrahend uint16, wide bool) uint16 {
-	var maxValue uint16
+// divideAndUpdateFlags divides AX or DX:AX by the divisor and stores the quotient in AL or AX and the remainder in AH or DX.
+// Returns the quotient and false without changing any registers if the divisor is 0 or the quotient does not fit.
+// Like on the 8086 the smallest negative quotient is not allowed for signed division. All flags are undefined.
+func (cpu *CPU) divideAndUpdateFlags(divisor uint16, signed, wide bool) (quotient uint16, valid bool) {
+	var remainder uint16
+	switch {
+	case !wide && !signed:
+		divisor &= _L
+		if divisor == 0 || cpu.AX/divisor > _L {
+			return 0, false
+		}
+		quotient, remainder = cpu.AX/divisor, cpu.AX%divisor
+	case !wide && signed:
+		dividend, signedDivisor := int32(int16(cpu.AX)), int32(int8(divisor))
+		if signedDivisor == 0 || dividend/signedDivisor > 127 || dividend/signedDivisor < -127 {
+			return 0, false
+		}
+		quotient, remainder = uint16(dividend/signedDivisor), uint16(dividend%signedDivisor)
+	case wide && !signed:
+		dividend := uint32(cpu.DX)<<16 | uint32(cpu.AX)
+		if divisor == 0 || dividend/uint32(divisor) > uint32(_W_MAX) {
+			return 0, false
+		}
+		quotient, remainder = uint16(dividend/uint32(divisor)), uint16(dividend%uint32(divisor))
+	case wide && signed:
+		dividend, signedDivisor := int64(int32(uint32(cpu.DX)<<16|uint32(cpu.AX))), int64(int16(divisor))
+		if signedDivisor == 0 || dividend/signedDivisor > 32767 || dividend/signedDivisor < -32767 {
+			return 0, false
+		}
+		quotient, remainder = uint16(dividend/signedDivisor), uint16(dividend%signedDivisor)
+	}
 	if wide {
-		maxValue = _W_MAX
+		cpu.AX = quotient
+		cpu.DX = remainder
 	} else {
-		maxValue = uint16(_B_MAX)
+		cpu.AX = writeH(writeL(cpu.AX, quotient), remainder&_L)
 	}
-	negatedSubtrahend := subtrahend ^ maxValue + 1
-	difference := addAndUpdateFlags(minuend, negatedSubtrahend, wide)
+	cpu.setUndefinedFlag(&cpu.CF)
+	cpu.setUndefinedFlag(&cpu.OF)
+	cpu.setUndefinedFlag(&cpu.SF)
+	cpu.setUndefinedFlag(&cpu.ZF)
+	cpu.setUndefinedFlag(&cpu.AF)
+	cpu.setUndefinedFlag(&cpu.PF)
+	return quotient, true
+}
+
+// shiftAndUpdateFlags shifts or rotates value count times by one bit, like the 8086 microcode loops once per bit.
+// CF and OF are set by the last step. The operation is defined by the register portion of the parameter.
+// The undocumented operation 0b110 is an alias of SAL.
+func (cpu *CPU) shiftAndUpdateFlags(operation byte, value uint16, count byte, wide bool) uint16 {
+	if count == 0 {
+		return value
+	}
+	maxValue, signBit := getMaxValueAndSignBit(wide)
+	var topBit uint16 = 7
+	if wide {
+		topBit = 15
+	}
+	value &= maxValue
+	for ; count > 0; count-- {
+		lowBit := byte(value & 1)
+		highBit := byte(value >> topBit & 1)
+		switch operation {
+		//ROL
+		case 0b000:
+			value = value<<1&maxValue | uint16(highBit)
+			cpu.CF = highBit
+			cpu.OF = byte(value>>topBit&1) ^ cpu.CF
+		//ROR
+		case 0b001:
+			value = value>>1 | uint16(lowBit)<<topBit
+			cpu.CF = lowBit
+			cpu.OF = byte(value>>topBit&1) ^ byte(value>>(topBit-1)&1)
+		//RCL
+		case 0b010:
+			value = value<<1&maxValue | uint16(cpu.CF)
+			cpu.CF = highBit
+			cpu.OF = byte(value>>topBit&1) ^ cpu.CF
+		//RCR
+		case 0b011:
+			value = value>>1 | uint16(cpu.CF)<<topBit
+			cpu.CF = lowBit
+			cpu.OF = byte(value>>topBit&1) ^ byte(value>>(topBit-1)&1)
+		//SHL/SAL
+		case 0b100:
+			fallthrough
+		case 0b110:
+			value = value << 1 & maxValue
+			cpu.CF = highBit
+			cpu.OF = byte(value>>topBit&1) ^ cpu.CF
+		//SHR
+		case 0b101:
+			value >>= 1
+			cpu.CF = lowBit
+			cpu.OF = highBit
+		//SAR
+		case 0b111:
+			value = value>>1 | value&signBit
+			cpu.CF = lowBit
+			cpu.OF = 0
+		}
+	}
+	//rotates only change CF and OF
+	if operation&0b100 != 0 {
+		cpu.setUndefinedFlag(&cpu.AF)
+		cpu.setCommonFlags(value, signBit)
+	}
+	return value
+}
+
+func (cpu *CPU) andAndUpdateFlags(operand1, operand2 uint16, wide bool) uint16 {
+	return cpu.updateLogicalFlags(operand1&operand2, wide)
+}
 
-	if minuend < subtrahend {
-		CF = 1
+func (cpu *CPU) orAndUpdateFlags(operand1, operand2 uint16, wide bool) uint16 {
+	return cpu.updateLogicalFlags(operand1|operand2, wide)
+}
+
+func (cpu *CPU) xorAndUpdateFlags(operand1, operand2 uint16, wide bool) uint16 {
+	return cpu.updateLogicalFlags(operand1^operand2, wide)
+}
+
+// updateLogicalFlags sets the flags for the result of a logical operation. CF and OF are cleared and AF is undefined.
+func (cpu *CPU) updateLogicalFlags(result uint16, wide bool) uint16 {
+	maxValue, signBit := getMaxValueAndSignBit(wide)
+	result &= maxValue
+	cpu.CF = 0
+	cpu.OF = 0
+	cpu.setUndefinedFlag(&cpu.AF)
+	cpu.setCommonFlags(result, signBit)
+	return result
+}
+
+// decimalAdjustAndUpdateFlags implements DAA and DAS by adding the BCD correction to or subtracting it from AL.
+// Like on the 8086 a set AF raises the threshold for correcting the upper digit to 0x9F and the undefined OF is the overflow of the correction.
+func (cpu *CPU) decimalAdjustAndUpdateFlags(subtract bool) {
+	al := cpu.AX & _L
+	adjustLower := al&0b1111 > 9 || cpu.AF != 0
+	threshold := uint16(0x99)
+	if cpu.AF != 0 {
+		threshold = 0x9F
+	}
+	adjustUpper := al > threshold || cpu.CF != 0
+
+	var correction uint16
+	if adjustLower {
+		correction |= 0x06
+	}
+	if adjustUpper {
+		correction |= 0x60
+	}
+	if subtract {
+		al = cpu.subAndUpateFlags(al, correction, false)
+	} else {
+		al = cpu.addAndUpdateFlags(al, correction, false)
+	}
+	cpu.AX = writeL(cpu.AX, al)
+
+	if adjustLower {
+		cpu.AF = 1
 	} else {
-		CF = 0
+		cpu.AF = 0
 	}
-	if minuend&0b1111 < subtrahend&0b1111 {
-		AF = 1
+	if adjustUpper {
+		cpu.CF = 1
 	} else {
-		AF = 0
+		cpu.CF = 0
 	}
+}
 
-	return difference
+// asciiAdjustAndUpdateFlags implements AAA and AAS. Like on the 8086 the correction of AL does not carry into AH
+// and the undefined OF, SF, ZF and PF are set by the correction of AL before its upper digit is cleared.
+func (cpu *CPU) asciiAdjustAndUpdateFlags(subtract bool) {
+	al, ah := cpu.AX&_L, readH(cpu.AX)
+	adjust := al&0b1111 > 9 || cpu.AF != 0
+
+	var correction uint16
+	if adjust {
+		correction = 6
+	}
+	if subtract {
+		al = cpu.subAndUpateFlags(al, correction, false)
+		if adjust {
+			ah = (ah - 1) & _L
+		}
+	} else {
+		al = cpu.addAndUpdateFlags(al, correction, false)
+		if adjust {
+			ah = (ah + 1) & _L
+		}
+	}
+	cpu.AX = writeH(al&0b1111, ah)
+
+	if adjust {
+		cpu.AF = 1
+		cpu.CF = 1
+	} else {
+		cpu.AF = 0
+		cpu.CF = 0
+	}
+}
+
+// asciiAdjustForMultiplyAndUpdateFlags implements AAM by storing AL divided by base in AH and the remainder in AL.
+// Returns false without changing any registers if base is 0. OF, AF and CF are undefined.
+func (cpu *CPU) asciiAdjustForMultiplyAndUpdateFlags(base byte) bool {
+	if base == 0 {
+		return false
+	}
+	al := cpu.AX & _L
+	cpu.AX = writeH(al%uint16(base), al/uint16(base))
+	cpu.setCommonFlags(cpu.AX&_L, 0b10000000)
+	cpu.setUndefinedFlag(&cpu.OF)
+	cpu.setUndefinedFlag(&cpu.AF)
+	cpu.setUndefinedFlag(&cpu.CF)
+	return true
+}
+
+// asciiAdjustForDivisionAndUpdateFlags implements AAD by adding AH multiplied by base to AL and clearing AH.
+// Like on the 8086 the undefined OF, AF and CF are set by the addition.
+func (cpu *CPU) asciiAdjustForDivisionAndUpdateFlags(base byte) {
+	cpu.AX = cpu.addAndUpdateFlags(cpu.AX&_L, readH(cpu.AX)*uint16(base), false)
+}
+
+func getMaxValueAndSignBit(wide bool) (maxValue, signBit uint16) {
+	if wide {
+		return _W_MAX, _W_SIGN
+	}
+	return uint16(_B_MAX), _B_SIGN
 }
 
 func wrapAdd(addend1, addend2 uint16) uint16 {
@@ -67,3 +352,7 @@ func wrapAdd(addend1, addend2 uint16) uint16 {
 func wrapIncrement(x uint16) uint16 {
 	return wrapAdd(x, 1)
 }
+
+func wrapSub(minuend, subtrahend uint16) uint16 {
+	return uint16((uint32(minuend) - uint32(subtrahend)) & uint32(_W_MAX))
+}
@@ -0,0 +1,19 @@
+package Simulation
+
+// ClockedDevice is a device that is driven by the CPU clock
+type ClockedDevice interface {
+	// Clock advances the device by the CPU cycles that passed since the last call
+	Clock(cycles int)
+}
+
+// AttachClockedDevice lets device follow the CPU clock. Devices are advanced after every instruction and interrupt.
+func (cpu *CPU) AttachClockedDevice(device ClockedDevice) {
+	cpu.clockedDevices = append(cpu.clockedDevices, device)
+}
+
+// clockDevices advances all clocked devices by cycles
+func (cpu *CPU) clockDevices(cycles int) {
+	for _, device := range cpu.clockedDevices {
+		device.Clock(cycles)
+	}
+}
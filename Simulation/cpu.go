@@ -0,0 +1,163 @@
+package Simulation
+
+import "github.com/P100sch/Intel8086Simulator/Simulation/Shared"
+
+// UndefinedFlagBehaviour defines how flags are set that are documented as undefined after an instruction
+type UndefinedFlagBehaviour byte
+
+const (
+	// UNDEFINED_FLAGS_CLEARED clears undefined flags
+	UNDEFINED_FLAGS_CLEARED UndefinedFlagBehaviour = iota
+	// UNDEFINED_FLAGS_SET sets undefined flags
+	UNDEFINED_FLAGS_SET
+	// UNDEFINED_FLAGS_PRESERVED leaves undefined flags unchanged
+	UNDEFINED_FLAGS_PRESERVED
+)
+
+// CPU contains the complete state of a simulated 8086 including its memory.
+// Every CPU is independent, so multiple machines can be simulated in parallel.
+type CPU struct {
+	AX uint16
+	BX uint16
+	CX uint16
+	DX uint16
+	SP uint16
+	BP uint16
+	SI uint16
+	DI uint16
+	IP uint16
+
+	CS uint16
+	DS uint16
+	SS uint16
+	ES uint16
+
+	TF byte
+	DF byte
+	IF byte
+	OF byte
+	SF byte
+	ZF byte
+	AF byte
+	PF byte
+	CF byte
+
+	// Memory contains the 1 MB address space followed by the high memory area, which is only reachable with the A20 gate enabled
+	Memory [ADDRESS_SPACE_SIZE + HIGH_MEMORY_SIZE]byte
+
+	// UndefinedFlags configures the value of flags the 8086 documentation leaves undefined
+	UndefinedFlags UndefinedFlagBehaviour
+	// OpcodeMode configures if undocumented opcodes are executed like on the 8086 or reported as errors
+	OpcodeMode Shared.OpcodeMode
+
+	// A20Enabled enables address line 20 like the A20 gate of a 286-era PC, so addresses above FFFF:000F do not wrap to 0.
+	// It is disabled by default, like on the 8086.
+	A20Enabled bool
+
+	// ReadOnlyWrites configures if writes to read-only memory are ignored or stop the simulation
+	ReadOnlyWrites ReadOnlyWriteBehaviour
+
+	// InterruptController drives the INTR pin, which is inactive if it is nil
+	InterruptController InterruptController
+
+	// ports contains the handlers mapped to the I/O bus
+	ports []portMapping
+	// memoryMappings contains the handlers mapped to the memory bus
+	memoryMappings []memoryMapping
+	// mappedPages marks the pages that contain mapped addresses
+	mappedPages [(ADDRESS_SPACE_SIZE + HIGH_MEMORY_SIZE + 1<<memoryPageShift - 1) >> memoryPageShift]bool
+	// readOnlyWritten is set with the address of the first write to read-only memory, until it is reported
+	readOnlyWritten      bool
+	readOnlyWriteAddress int
+	// clockedDevices are advanced by the cycles of every instruction
+	clockedDevices []ClockedDevice
+	// nmiPending is set by a rising edge on the NMI pin until the NMI is recognized
+	nmiPending bool
+
+	// segmentOverride contains the segment register selected by a segment override prefix of the current instruction
+	segmentOverride   byte
+	segmentOverridden bool
+	// repeatPrefix contains the REP/REPNE prefix of the current instruction or 0
+	repeatPrefix byte
+	// fetched contains the bytes of the current instruction read so far, starting at CS:fetchStart
+	fetched    []byte
+	fetchStart uint16
+}
+
+// NewCPU creates a CPU in its reset state
+func NewCPU() *CPU {
+	return &CPU{CS: RESET_CS}
+}
+
+// Reset sets all registers and flags to their reset state and clears memory. The configuration and attached devices are kept.
+func (cpu *CPU) Reset() {
+	cpu.AX = 0
+	cpu.BX = 0
+	cpu.CX = 0
+	cpu.DX = 0
+	cpu.SP = 0
+	cpu.BP = 0
+	cpu.SI = 0
+	cpu.DI = 0
+	cpu.IP = 0
+	cpu.CS = RESET_CS
+	cpu.DS = 0
+	cpu.SS = 0
+	cpu.ES = 0
+	cpu.TF = 0
+	cpu.DF = 0
+	cpu.IF = 0
+	cpu.OF = 0
+	cpu.SF = 0
+	cpu.ZF = 0
+	cpu.AF = 0
+	cpu.PF = 0
+	cpu.CF = 0
+	cpu.clearPrefixes()
+	cpu.nmiPending = false
+	cpu.readOnlyWritten = false
+
+	clear(cpu.Memory[:])
+}
+
+// Flags packs the flags into the 8086 FLAGS word. Reserved bits 1 and 12-15 read as 1, bits 3 and 5 as 0.
+func (cpu *CPU) Flags() uint16 {
+	return 0b1111000000000010 |
+		uint16(cpu.OF)<<11 |
+		uint16(cpu.DF)<<10 |
+		uint16(cpu.IF)<<9 |
+		uint16(cpu.TF)<<8 |
+		uint16(cpu.SF)<<7 |
+		uint16(cpu.ZF)<<6 |
+		uint16(cpu.AF)<<4 |
+		uint16(cpu.PF)<<2 |
+		uint16(cpu.CF)
+}
+
+// SetFlags unpacks the 8086 FLAGS word into the separate flags. Reserved bits are ignored.
+func (cpu *CPU) SetFlags(value uint16) {
+	cpu.OF = byte(value >> 11 & 1)
+	cpu.DF = byte(value >> 10 & 1)
+	cpu.IF = byte(value >> 9 & 1)
+	cpu.TF = byte(value >> 8 & 1)
+	cpu.SF = byte(value >> 7 & 1)
+	cpu.ZF = byte(value >> 6 & 1)
+	cpu.AF = byte(value >> 4 & 1)
+	cpu.PF = byte(value >> 2 & 1)
+	cpu.CF = byte(value & 1)
+}
+
+// clearPrefixes resets the prefixes after an instruction is complete
+func (cpu *CPU) clearPrefixes() {
+	cpu.segmentOverride = 0
+	cpu.segmentOverridden = false
+	cpu.repeatPrefix = 0
+}
+
+// applySegmentOverride gets the segment selected by a segment override prefix or the default segment if there is none
+func (cpu *CPU) applySegmentOverride(defaultSegment uint16) uint16 {
+	if cpu.segmentOverridden {
+		return cpu.readSegmentRegister(cpu.segmentOverride)
+	}
+	return defaultSegment
+}
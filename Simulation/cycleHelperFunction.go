@@ -1,6 +1,10 @@
 package Simulation
 
-import "github.com/P100sch/Intel8086Simulator/Simulation/Shared"
+import (
+	"math/bits"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation/Shared"
+)
 
 func getBaseDecodingAndPenaltyCyclesByParameter(parameter byte, virtualDataAddress uint16, sourceInReg, wide, readOnce bool, regCycles, fromMemoryCycles, toMemoryCycles int) (baseCycles, decodingCycles, penaltyCycles int) {
 	if parameter&Shared.ModMask == Shared.RegisterMode {
@@ -46,3 +50,31 @@ func getBaseDecodingAndPenaltyCyclesByParameter(parameter byte, virtualDataAddre
 	}
 	return
 }
+
+// getWordTransferPenaltyCycles gets the penalty for a word transfer, which needs an additional bus cycle on odd addresses
+func getWordTransferPenaltyCycles(address uint16) int {
+	return int(address&1) * 4
+}
+
+// getDataDependentCycles estimates the cycles of an instruction with a data dependent cycle range from the 8086 manual.
+// The microcode loops once per bit, so the range is interpolated by the number of set bits in value.
+func getDataDependentCycles(minCycles, maxCycles int, value uint16, wide bool) int {
+	if wide {
+		return minCycles + bits.OnesCount16(value)*(maxCycles-minCycles)/16
+	}
+	return minCycles + bits.OnesCount8(uint8(value))*(maxCycles-minCycles)/8
+}
+
+// getAbsoluteValue gets the magnitude of a signed byte or word
+func getAbsoluteValue(value uint16, wide bool) uint16 {
+	if wide {
+		if int16(value) < 0 {
+			return -value
+		}
+		return value
+	}
+	if int8(value) < 0 {
+		return uint16(-int8(value)) & _L
+	}
+	return value & _L
+}
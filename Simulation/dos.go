@@ -0,0 +1,236 @@
+package Simulation
+
+import "encoding/binary"
+
+const (
+	// PSP_SIZE is the size of the Program Segment Prefix DOS places in front of every program
+	PSP_SIZE = 0x100
+	// COM_STACK_POINTER is the initial SP of .COM programs, which points to a zero word at the end of the segment
+	COM_STACK_POINTER uint16 = 0xFFFE
+	// DOS_MEMORY_TOP_SEGMENT is the segment after the 640 KB of conventional memory
+	DOS_MEMORY_TOP_SEGMENT uint16 = 0xA000
+	// DEFAULT_PSP_SEGMENT is the segment programs are loaded at if no other segment is chosen
+	DEFAULT_PSP_SEGMENT uint16 = 0x1000
+	// MAX_COMMAND_TAIL_LENGTH is the number of characters of the command tail that fit into the PSP before the carriage return
+	MAX_COMMAND_TAIL_LENGTH = 126
+)
+
+// offsets into the PSP
+const (
+	pspMemoryTop       = 0x02
+	pspTerminateVector = 0x0A
+	pspEnvironment     = 0x2C
+	pspHaltStub        = 0x42
+	pspDOSCall         = 0x50
+	pspFCB1            = 0x5C
+	pspFCB2            = 0x6C
+	pspCommandTail     = 0x80
+)
+
+// DOS interrupts, which end the simulation if no DOS is installed
+const (
+	DOS_TERMINATE_INTERRUPT byte = 0x20
+	DOS_FUNCTION_INTERRUPT  byte = 0x21
+)
+
+// LoadError is returned if an executable is invalid or does not fit into memory
+type LoadError string
+
+func (e LoadError) Error() string {
+	return "load error: " + string(e)
+}
+
+// LoadCOM loads a DOS .COM program like COMMAND.COM. A PSP with commandTail is built at segment:0000,
+// the image is loaded at segment:0100 and CS, DS, ES and SS are set to segment with SP at 0xFFFE.
+// The command tail is stored as is, so like in DOS it should start with the space after the program name.
+// The general registers are cleared like DOS does, which also reports both FCB drives as valid in AL and AH.
+// No DOS services are emulated. If their vectors are unset, INT 20h and INT 21h halt the simulation,
+// so programs that return to the PSP or terminate through DOS end cleanly.
+func (cpu *CPU) LoadCOM(data []byte, segment uint16, commandTail string) error {
+	if len(data) > int(COM_STACK_POINTER)-PSP_SIZE {
+		return LoadError("program too big")
+	}
+	if err := checkProgramSegment(segment, 0x1000); err != nil {
+		return err
+	}
+	//the program owns at least its 64 KB segment
+	memoryTop := uint16(min(max(int(DOS_MEMORY_TOP_SEGMENT), int(segment)+0x1000), int(_W_MAX)))
+	if err := cpu.buildPSP(segment, memoryTop, commandTail); err != nil {
+		return err
+	}
+	base := int(segment) << 4
+	cpu.loadPhysical(base+PSP_SIZE, data)
+	//returning with RET executes INT 20h at the start of the PSP
+	cpu.loadPhysical(base+int(COM_STACK_POINTER), []byte{0, 0})
+
+	cpu.CS = segment
+	cpu.DS = segment
+	cpu.ES = segment
+	cpu.SS = segment
+	cpu.IP = PSP_SIZE
+	cpu.SP = COM_STACK_POINTER
+	cpu.startProgram()
+	return nil
+}
+
+// checkProgramSegment checks if paragraphs starting at segment fit between the interrupt vector table and the end of the address space
+func checkProgramSegment(segment uint16, paragraphs int) error {
+	if int(segment)<<4 < 0x400 {
+		return LoadError("program segment overlaps the interrupt vector table")
+	}
+	if (int(segment)+paragraphs)<<4 > ADDRESS_SPACE_SIZE {
+		return LoadError("program segment exceeds the address space")
+	}
+	return nil
+}
+
+// buildPSP writes a Program Segment Prefix to segment:0000 and points unset DOS vectors to a HLT inside of it
+func (cpu *CPU) buildPSP(segment, memoryTop uint16, commandTail string) error {
+	if len(commandTail) > MAX_COMMAND_TAIL_LENGTH {
+		return LoadError("command tail too long")
+	}
+	var psp [PSP_SIZE]byte
+
+	//INT 20h
+	psp[0] = 0b11001101
+	psp[1] = DOS_TERMINATE_INTERRUPT
+	binary.LittleEndian.PutUint16(psp[pspMemoryTop:], memoryTop)
+	//the vectors of INT 22h to 24h are saved to restore them when the program terminates
+	cpu.readPhysicalBlock(0x22<<2, psp[pspTerminateVector:pspTerminateVector+12])
+	//no environment
+	binary.LittleEndian.PutUint16(psp[pspEnvironment:], 0)
+	//INT 21h, RETF
+	psp[pspDOSCall] = 0b11001101
+	psp[pspDOSCall+1] = DOS_FUNCTION_INTERRUPT
+	psp[pspDOSCall+2] = 0b11001011
+	//empty file control blocks for the default drive
+	for _, fcb := range []int{pspFCB1, pspFCB2} {
+		for i := 1; i <= 11; i++ {
+			psp[fcb+i] = ' '
+		}
+	}
+	psp[pspCommandTail] = byte(len(commandTail))
+	copy(psp[pspCommandTail+1:], commandTail)
+	psp[pspCommandTail+1+len(commandTail)] = '\r'
+
+	//HLT
+	psp[pspHaltStub] = 0b11110100
+	cpu.loadPhysical(int(segment)<<4, psp[:])
+	for _, interruptType := range []byte{DOS_TERMINATE_INTERRUPT, DOS_FUNCTION_INTERRUPT} {
+		var vector [4]byte
+		cpu.readPhysicalBlock(int(interruptType)<<2, vector[:])
+		if binary.LittleEndian.Uint32(vector[:]) == 0 {
+			binary.LittleEndian.PutUint16(vector[:], pspHaltStub)
+			binary.LittleEndian.PutUint16(vector[2:], segment)
+			cpu.loadPhysical(int(interruptType)<<2, vector[:])
+		}
+	}
+	return nil
+}
+
+// offsets into the header of MZ executables
+const (
+	mzLastPageSize      = 0x02
+	mzPages             = 0x04
+	mzRelocations       = 0x06
+	mzHeaderParagraphs  = 0x08
+	mzMinimumAllocation = 0x0A
+	mzMaximumAllocation = 0x0C
+	mzSS                = 0x0E
+	mzSP                = 0x10
+	mzIP                = 0x14
+	mzCS                = 0x16
+	mzRelocationTable   = 0x18
+	mzHeaderSize        = 0x1C
+)
+
+// LoadEXE loads a DOS MZ executable. A PSP with commandTail is built at segment:0000 and the image is loaded
+// directly behind it or, if the minimum and maximum allocation are both 0, at the top of conventional memory.
+// The relocation table is applied against the load segment and CS:IP and SS:SP are taken from the header.
+// DS and ES point to the PSP. Like DOS the program gets as many paragraphs as its maximum allocation asks for,
+// as long as they fit below 640 KB, and loading fails if not even the minimum allocation fits.
+// The general registers are cleared and no DOS services are emulated, see LoadCOM.
+func (cpu *CPU) LoadEXE(data []byte, segment uint16, commandTail string) error {
+	if len(data) < mzHeaderSize || !(data[0] == 'M' && data[1] == 'Z' || data[0] == 'Z' && data[1] == 'M') {
+		return LoadError("missing MZ header")
+	}
+	header := func(offset int) int {
+		return int(binary.LittleEndian.Uint16(data[offset:]))
+	}
+
+	fileSize := header(mzPages) * 512
+	if lastPageSize := header(mzLastPageSize); lastPageSize != 0 {
+		fileSize -= 512 - lastPageSize
+	}
+	headerSize := header(mzHeaderParagraphs) << 4
+	if headerSize < mzHeaderSize || fileSize < headerSize || fileSize > len(data) {
+		return LoadError("invalid MZ header sizes")
+	}
+	image := data[headerSize:fileSize]
+	relocationTable := header(mzRelocationTable)
+	relocations := header(mzRelocations)
+	if relocationTable+relocations*4 > len(data) {
+		return LoadError("relocation table exceeds the file")
+	}
+
+	if err := checkProgramSegment(segment, PSP_SIZE>>4); err != nil {
+		return err
+	}
+	if segment >= DOS_MEMORY_TOP_SEGMENT {
+		return LoadError("not enough memory")
+	}
+	imageParagraphs := (len(image) + 0xF) >> 4
+	availableParagraphs := int(DOS_MEMORY_TOP_SEGMENT - segment)
+	minimumParagraphs := PSP_SIZE>>4 + imageParagraphs + header(mzMinimumAllocation)
+	if minimumParagraphs > availableParagraphs {
+		return LoadError("not enough memory")
+	}
+	allocatedParagraphs := min(PSP_SIZE>>4+imageParagraphs+header(mzMaximumAllocation), availableParagraphs)
+	memoryTop := segment + uint16(allocatedParagraphs)
+
+	loadSegment := segment + PSP_SIZE>>4
+	if header(mzMinimumAllocation) == 0 && header(mzMaximumAllocation) == 0 {
+		//load high
+		memoryTop = DOS_MEMORY_TOP_SEGMENT
+		loadSegment = memoryTop - uint16(imageParagraphs)
+	}
+
+	if err := cpu.buildPSP(segment, memoryTop, commandTail); err != nil {
+		return err
+	}
+	loadAddress := int(loadSegment) << 4
+	cpu.loadPhysical(loadAddress, image)
+	for i := range relocations {
+		entry := relocationTable + i*4
+		address := loadAddress + header(entry+2)<<4 + header(entry)
+		if address+2 > loadAddress+len(image) {
+			return LoadError("relocation outside of the image")
+		}
+		var word [2]byte
+		cpu.readPhysicalBlock(address, word[:])
+		binary.LittleEndian.PutUint16(word[:], wrapAdd(binary.LittleEndian.Uint16(word[:]), loadSegment))
+		cpu.loadPhysical(address, word[:])
+	}
+
+	cpu.CS = wrapAdd(loadSegment, uint16(header(mzCS)))
+	cpu.IP = uint16(header(mzIP))
+	cpu.SS = wrapAdd(loadSegment, uint16(header(mzSS)))
+	cpu.SP = uint16(header(mzSP))
+	cpu.DS = segment
+	cpu.ES = segment
+	cpu.startProgram()
+	return nil
+}
+
+// startProgram sets the general registers and flags a program gets from DOS
+func (cpu *CPU) startProgram() {
+	cpu.AX = 0
+	cpu.BX = 0
+	cpu.CX = 0
+	cpu.DX = 0
+	cpu.SI = 0
+	cpu.DI = 0
+	cpu.BP = 0
+	//DOS starts programs with interrupts enabled
+	cpu.IF = 1
+}
@@ -25,107 +25,147 @@ func writeH(w, b uint16) uint16 {
 	return w&_L | b<<8
 }
 
-func readRegister(register byte) uint16 {
+func (cpu *CPU) readRegister(register byte) uint16 {
 	switch register {
 	case 0b0000:
-		return AX & _L
+		return cpu.AX & _L
 	case 0b0001:
-		return CX & _L
+		return cpu.CX & _L
 	case 0b0010:
-		return DX & _L
+		return cpu.DX & _L
 	case 0b0011:
-		return BX & _L
+		return cpu.BX & _L
 	case 0b0100:
-		return readH(AX)
+		return readH(cpu.AX)
 	case 0b0101:
-		return readH(CX)
+		return readH(cpu.CX)
 	case 0b0110:
-		return readH(DX)
+		return readH(cpu.DX)
 	case 0b0111:
-		return readH(BX)
+		return readH(cpu.BX)
 	case 0b1000:
-		return AX
+		return cpu.AX
 	case 0b1001:
-		return CX
+		return cpu.CX
 	case 0b1010:
-		return DX
+		return cpu.DX
 	case 0b1011:
-		return BX
+		return cpu.BX
 	case 0b1100:
-		return SP
+		return cpu.SP
 	case 0b1101:
-		return BP
+		return cpu.BP
 	case 0b1110:
-		return SI
+		return cpu.SI
 	case 0b1111:
-		return DI
+		return cpu.DI
 	default:
 		panic("Invalid register value")
 	}
 }
 
-func writeRegister(register byte, value uint16) {
+func (cpu *CPU) writeRegister(register byte, value uint16) {
 	switch register {
 	case 0b0000:
-		AX = writeL(AX, value)
+		cpu.AX = writeL(cpu.AX, value)
 	case 0b0001:
-		CX = writeL(CX, value)
+		cpu.CX = writeL(cpu.CX, value)
 	case 0b0010:
-		DX = writeL(DX, value)
+		cpu.DX = writeL(cpu.DX, value)
 	case 0b0011:
-		BX = writeL(BX, value)
+		cpu.BX = writeL(cpu.BX, value)
 	case 0b0100:
-		AX = writeH(AX, value)
+		cpu.AX = writeH(cpu.AX, value)
 	case 0b0101:
-		CX = writeH(CX, value)
+		cpu.CX = writeH(cpu.CX, value)
 	case 0b0110:
-		DX = writeH(DX, value)
+		cpu.DX = writeH(cpu.DX, value)
 	case 0b0111:
-		BX = writeH(BX, value)
+		cpu.BX = writeH(cpu.BX, value)
 	case 0b1000:
-		AX = value
+		cpu.AX = value
 	case 0b1001:
-		CX = value
+		cpu.CX = value
 	case 0b1010:
-		DX = value
+		cpu.DX = value
 	case 0b1011:
-		BX = value
+		cpu.BX = value
 	case 0b1100:
-		SP = value
+		cpu.SP = value
 	case 0b1101:
-		BP = value
+		cpu.BP = value
 	case 0b1110:
-		SI = value
+		cpu.SI = value
 	case 0b1111:
-		DI = value
+		cpu.DI = value
 	default:
 		panic("Invalid register value")
 	}
 }
 
+func (cpu *CPU) readSegmentRegister(segmentRegister byte) uint16 {
+	switch segmentRegister {
+	case 0b00:
+		return cpu.ES
+	case 0b01:
+		return cpu.CS
+	case 0b10:
+		return cpu.SS
+	case 0b11:
+		return cpu.DS
+	default:
+		panic("Invalid segment register value")
+	}
+}
+
+func (cpu *CPU) writeSegmentRegister(segmentRegister byte, value uint16) {
+	switch segmentRegister {
+	case 0b00:
+		cpu.ES = value
+	case 0b01:
+		cpu.CS = value
+	case 0b10:
+		cpu.SS = value
+	case 0b11:
+		cpu.DS = value
+	default:
+		panic("Invalid segment register value")
+	}
+}
+
 func signExtend(x uint16) uint16 {
 	signExtension := x & 0b10000000 >> 7 * _H
 	return signExtension | x
 }
 
-func setCommonFlags(value uint16, signBit uint16) {
+func (cpu *CPU) setCommonFlags(value uint16, signBit uint16) {
 	if value == 0 {
-		ZF = 1
+		cpu.ZF = 1
 	} else {
-		ZF = 0
+		cpu.ZF = 0
 	}
 	odd := (value & 0b11110000 >> 4) ^ (value & 0b1111)
 	odd = (odd & 0b1100 >> 2) ^ (odd & 0b11)
 	odd = (odd & 0b10 >> 1) ^ (odd & 0b1)
 	if odd == 1 {
-		PF = 0
+		cpu.PF = 0
 	} else {
-		PF = 1
+		cpu.PF = 1
 	}
 	if value&signBit == 0 {
-		SF = 0
+		cpu.SF = 0
 	} else {
-		SF = 1
+		cpu.SF = 1
+	}
+}
+
+// setUndefinedFlag sets a flag that is undefined after the current instruction according to the configuration
+func (cpu *CPU) setUndefinedFlag(flag *byte) {
+	switch cpu.UndefinedFlags {
+	case UNDEFINED_FLAGS_CLEARED:
+		*flag = 0
+	case UNDEFINED_FLAGS_SET:
+		*flag = 1
 	}
 }
 
@@ -137,43 +177,39 @@ func calculateJumpB(offset uint8, currentOffset uint16) uint16 {
 	return calculateJump(signExtend(uint16(offset)), currentOffset)
 }
 
-func calculateSegmentAndDisplacementByParameter(parameter byte, parameterOffset uint16) (segment, displacement uint16) {
+func (cpu *CPU) calculateSegmentAndDisplacementByParameter(parameter byte, parameterOffset uint16) (segment, displacement uint16) {
 	displacementOffset := wrapIncrement(parameterOffset)
-	segment = DS
+	segment = cpu.DS
 	rm := parameter & Shared.RMMask
 	switch rm {
 	case 0b000:
-		displacement = wrapAdd(BX, SI)
+		displacement = wrapAdd(cpu.BX, cpu.SI)
 	case 0b001:
-		displacement = wrapAdd(BX, DI)
+		displacement = wrapAdd(cpu.BX, cpu.DI)
 	case 0b010:
-		displacement = wrapAdd(BP, SI)
+		displacement = wrapAdd(cpu.BP, cpu.SI)
 	case 0b011:
-		displacement = wrapAdd(BP, DI)
+		displacement = wrapAdd(cpu.BP, cpu.DI)
 	case 0b100:
-		displacement = SI
+		displacement = cpu.SI
 	case 0b101:
-		displacement = DI
+		displacement = cpu.DI
 	case 0b110:
-		displacement = BP
-		segment = SS
+		displacement = cpu.BP
+		segment = cpu.SS
 	case 0b111:
-		displacement = BX
+		displacement = cpu.BX
 	}
 	switch parameter & Shared.ModMask {
 	case Shared.MemoryMode:
-		if rm != 0b110 {
-			return
+		if rm == 0b110 {
+			segment = cpu.DS
+			displacement = cpu.readCodeW(displacementOffset)
 		}
-		segment = DS
-		displacement = readCodeW(displacementOffset)
-		return
 	case Shared.Memory8Mode:
-		displacement = wrapAdd(displacement, uint16(readCodeB(displacementOffset)))
-		return
+		displacement = wrapAdd(displacement, signExtend(uint16(cpu.readCodeB(displacementOffset))))
 	case Shared.Memory16Mode:
-		displacement = wrapAdd(displacement, readCodeW(displacementOffset))
-		return
+		displacement = wrapAdd(displacement, cpu.readCodeW(displacementOffset))
 	case Shared.RegisterMode:
 		segment = 0
 		displacement = 0
@@ -181,6 +217,8 @@ func calculateSegmentAndDisplacementByParameter(parameter byte, parameterOffset
 	default:
 		panic("impossible state")
 	}
+	segment = cpu.applySegmentOverride(segment)
+	return
 }
 
 func incrementIPByParameter(currentIP uint16, parameter byte) uint16 {
@@ -201,20 +239,20 @@ func incrementIPByParameter(currentIP uint16, parameter byte) uint16 {
 	return newIP
 }
 
-func readRMValueSegmentAndDisplacementByParameter(parameter byte, parameterOffset uint16, wide byte) (value, segment, displacement uint16) {
+func (cpu *CPU) readRMValueSegmentAndDisplacementByParameter(parameter byte, parameterOffset uint16, wide byte) (value, segment, displacement uint16) {
 	if parameter&Shared.ModMask != Shared.RegisterMode {
-		segment, displacement = calculateSegmentAndDisplacementByParameter(parameter, parameterOffset)
-		value = read(segment, displacement, wide != 0)
+		segment, displacement = cpu.calculateSegmentAndDisplacementByParameter(parameter, parameterOffset)
+		value = cpu.read(segment, displacement, wide != 0)
 	} else {
-		value = readRegister(wide | parameter&Shared.RMMask)
+		value = cpu.readRegister(wide | parameter&Shared.RMMask)
 	}
 	return
 }
 
-func writeRMValue(parameter byte, segment, displacement, value uint16, wide byte) {
+func (cpu *CPU) writeRMValue(parameter byte, segment, displacement, value uint16, wide byte) {
 	if parameter&Shared.ModMask != Shared.RegisterMode {
-		write(segment, displacement, value, wide != 0)
+		cpu.write(segment, displacement, value, wide != 0)
 	} else {
-		writeRegister(wide|parameter&Shared.RMMask, value)
+		cpu.writeRegister(wide|parameter&Shared.RMMask, value)
 	}
 }
@@ -0,0 +1,90 @@
+package Simulation
+
+// Interrupt types with a predefined meaning on the 8086
+const (
+	DIVIDE_ERROR_INTERRUPT byte = 0
+	SINGLE_STEP_INTERRUPT  byte = 1
+	NMI_INTERRUPT          byte = 2
+	BREAKPOINT_INTERRUPT   byte = 3
+	OVERFLOW_INTERRUPT     byte = 4
+)
+
+// InterruptController drives the INTR pin of a CPU
+type InterruptController interface {
+	// InterruptRequested gets the level of the INTR pin
+	InterruptRequested() bool
+	// AcknowledgeInterrupt is called for the interrupt acknowledge cycle and returns the interrupt type
+	AcknowledgeInterrupt() byte
+	// InterruptPossible reports if an input can still request an interrupt, so a halted CPU waits for it
+	InterruptPossible() bool
+}
+
+// MAX_HALT_CYCLES is the number of cycles a halted CPU waits for an interrupt before the simulation fails.
+// It covers several periods of the slowest PIT timer.
+const MAX_HALT_CYCLES = 1 << 22
+
+// TriggerNMI signals a rising edge on the NMI pin. The NMI is recognized at the next instruction boundary regardless of IF.
+func (cpu *CPU) TriggerNMI() {
+	cpu.nmiPending = true
+}
+
+// recognizeInterrupt checks for an interrupt at an instruction boundary. NMI has the highest priority,
+// followed by INTR, if IF is set, and the single step trap.
+// Returns the interrupt type and the cycles needed to enter the handler.
+func (cpu *CPU) recognizeInterrupt(trap bool) (interruptType byte, cycles int, recognized bool) {
+	switch {
+	case cpu.nmiPending:
+		cpu.nmiPending = false
+		return NMI_INTERRUPT, 50, true
+	case cpu.IF != 0 && cpu.InterruptController != nil && cpu.InterruptController.InterruptRequested():
+		return cpu.InterruptController.AcknowledgeInterrupt(), 61, true
+	case trap:
+		return SINGLE_STEP_INTERRUPT, 50, true
+	}
+	return 0, 0, false
+}
+
+// canLeaveHalt checks if an interrupt can end the halt state. Besides pending interrupts only a clocked device
+// can raise an interrupt while the CPU is halted, which needs IF and an interrupt controller that can still request it.
+func (cpu *CPU) canLeaveHalt(trap bool) bool {
+	if trap || cpu.nmiPending {
+		return true
+	}
+	if cpu.IF == 0 || cpu.InterruptController == nil {
+		return false
+	}
+	return cpu.InterruptController.InterruptRequested() || len(cpu.clockedDevices) != 0 && cpu.InterruptController.InterruptPossible()
+}
+
+// waitForInterrupt clocks the devices cycle by cycle until an interrupt is pending or MAX_HALT_CYCLES passed.
+// Returns the cycles spent halted and if an interrupt is pending.
+func (cpu *CPU) waitForInterrupt(trap bool) (cycles int, pending bool) {
+	for ; cycles < MAX_HALT_CYCLES; cycles++ {
+		if trap || cpu.nmiPending || cpu.IF != 0 && cpu.InterruptController != nil && cpu.InterruptController.InterruptRequested() {
+			return cycles, true
+		}
+		cpu.clockDevices(1)
+	}
+	return cycles, false
+}
+
+// interrupt pushes FLAGS, CS and IP, clears IF and TF and transfers control to the handler of interruptType.
+// The handler address is read from the interrupt vector table at 0000:0000.
+// IP needs to point to the instruction that is executed after returning from the handler.
+func (cpu *CPU) interrupt(interruptType byte) {
+	cpu.push(cpu.Flags())
+	cpu.IF = 0
+	cpu.TF = 0
+	cpu.push(cpu.CS)
+	cpu.push(cpu.IP)
+	vector := uint16(interruptType) << 2
+	cpu.IP = cpu.readW(0, vector)
+	cpu.CS = cpu.readW(0, vector+2)
+}
+
+// interruptReturn restores IP, CS and FLAGS from the stack
+func (cpu *CPU) interruptReturn() {
+	cpu.IP = cpu.pop()
+	cpu.CS = cpu.pop()
+	cpu.SetFlags(cpu.pop())
+}
@@ -0,0 +1,95 @@
+package Simulation
+
+import (
+	"log"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation/Shared"
+)
+
+// IOHandler emulates a device on the I/O bus.
+// Word accesses are only passed to a handler if the port is even and both ports are mapped to it,
+// otherwise they are split into two byte accesses like on the 8086.
+type IOHandler interface {
+	// In reads a byte or word from port
+	In(port uint16, wide bool) uint16
+	// Out writes a byte or word to port
+	Out(port, value uint16, wide bool)
+}
+
+type portMapping struct {
+	first, last uint16
+	handler     IOHandler
+}
+
+// MapPorts maps the ports from first to last, inclusive, to handler. Later mappings take precedence over earlier ones.
+func (cpu *CPU) MapPorts(first, last uint16, handler IOHandler) {
+	cpu.ports = append(cpu.ports, portMapping{first: first, last: last, handler: handler})
+}
+
+// getPortMapping gets the index of the mapping responsible for port or -1 if port is unmapped
+func (cpu *CPU) getPortMapping(port uint16) int {
+	for i := len(cpu.ports) - 1; i >= 0; i-- {
+		if cpu.ports[i].first <= port && port <= cpu.ports[i].last {
+			return i
+		}
+	}
+	return -1
+}
+
+// in reads a byte or word from port. Unmapped ports read as 0xFF and a warning is logged, if logger is not nil.
+func (cpu *CPU) in(port uint16, wide bool, logger *log.Logger) uint16 {
+	mapping := cpu.getPortMapping(port)
+	if wide {
+		if port&1 == 0 && mapping != -1 && mapping == cpu.getPortMapping(port+1) {
+			return cpu.ports[mapping].handler.In(port, true)
+		}
+		return cpu.in(port, false, logger) | cpu.in(wrapIncrement(port), false, logger)<<8
+	}
+	if mapping == -1 {
+		if logger != nil {
+			logger.Printf("warning: IN from unmapped port 0x%04x", port)
+		}
+		return uint16(_B_MAX)
+	}
+	return cpu.ports[mapping].handler.In(port, false) & _L
+}
+
+// out writes a byte or word to port. Writes to unmapped ports are ignored and a warning is logged, if logger is not nil.
+func (cpu *CPU) out(port, value uint16, wide bool, logger *log.Logger) {
+	mapping := cpu.getPortMapping(port)
+	if wide {
+		if port&1 == 0 && mapping != -1 && mapping == cpu.getPortMapping(port+1) {
+			cpu.ports[mapping].handler.Out(port, value, true)
+			return
+		}
+		cpu.out(port, value&_L, false, logger)
+		cpu.out(wrapIncrement(port), readH(value), false, logger)
+		return
+	}
+	if mapping == -1 {
+		if logger != nil {
+			logger.Printf("warning: OUT to unmapped port 0x%04x", port)
+		}
+		return
+	}
+	cpu.ports[mapping].handler.Out(port, value&_L, false)
+}
+
+// transferPort executes IN or OUT defined by opcode between the accumulator and port.
+// Returns the penalty cycles for word transfers at odd ports.
+func (cpu *CPU) transferPort(opcode byte, port uint16, logger *log.Logger) (penaltyCycles int) {
+	wide := opcode&Shared.WideMask != 0
+	if opcode&Shared.DirectionMask == 0 {
+		if wide {
+			cpu.AX = cpu.in(port, true, logger)
+		} else {
+			cpu.AX = writeL(cpu.AX, cpu.in(port, false, logger))
+		}
+	} else {
+		cpu.out(port, cpu.AX, wide, logger)
+	}
+	if wide {
+		penaltyCycles = getWordTransferPenaltyCycles(port)
+	}
+	return
+}
@@ -3,7 +3,6 @@ package Simulation
 import (
 	"fmt"
 	"log"
-	"slices"
 	"strconv"
 	"strings"
 
@@ -11,18 +10,19 @@ import (
 )
 
 // end exclusive
-func readInstruction(start, end uint16) []byte {
-	baseAddress := int(CS) << 4
-	if start > end {
-		return slices.Concat(Memory[baseAddress+int(start):], Memory[:baseAddress+int(end)+1])
-	} else {
-		return Memory[baseAddress+int(start) : baseAddress+int(end)+1]
+func (cpu *CPU) readInstruction(start, end uint16) []byte {
+	instruction := make([]byte, 0, int(end-start)+1)
+	for offset := start; ; offset = wrapIncrement(offset) {
+		instruction = append(instruction, cpu.readCodeB(offset))
+		if offset == end {
+			return instruction
+		}
 	}
 }
 
-func logStateAndInstruction(instruction []byte, instructionClocks, decodingClocks, penaltyClocks, totalClocks int, logger *log.Logger) {
+func (cpu *CPU) logStateAndInstruction(instruction []byte, instructionClocks, decodingClocks, penaltyClocks, totalClocks int, logger *log.Logger) {
 	if logger != nil {
-		assembly, err := Disassembly.Disassemble(instruction)
+		assembly, err := Disassembly.DisassembleWithOpcodeMode(instruction, cpu.OpcodeMode)
 		if err != nil {
 			logger.Println(err.Error())
 			return
@@ -30,79 +30,107 @@ func logStateAndInstruction(instruction []byte, instructionClocks, decodingClock
 
 		builder := strings.Builder{}
 
-		builder.WriteString(formatState())
+		builder.WriteString(cpu.formatState())
 		builder.WriteString(" ; ")
 		builder.WriteString(assembly)
-		builder.WriteString(" +")
-		builder.WriteString(strconv.Itoa(instructionClocks + decodingClocks + penaltyClocks))
-		builder.WriteString(" = ")
-		builder.WriteString(strconv.Itoa(totalClocks))
-		if decodingClocks != 0 || penaltyClocks != 0 {
-			builder.WriteString(" (")
-			builder.WriteString(strconv.Itoa(instructionClocks))
-			if decodingClocks != 0 {
-				builder.WriteString(" + ")
-				builder.WriteString(strconv.Itoa(decodingClocks))
-				builder.WriteString("ea")
-			}
-			if penaltyClocks != 0 {
-				builder.WriteString(" + ")
-				builder.WriteString(strconv.Itoa(penaltyClocks))
-				builder.WriteString("p")
-			}
-			builder.WriteString(")")
-		}
+		writeClocks(&builder, instructionClocks, decodingClocks, penaltyClocks, totalClocks)
+		logger.Println(builder.String())
+	}
+}
+
+// logInterrupt logs the state after an interrupt was recognized between instructions
+func (cpu *CPU) logInterrupt(interruptType byte, interruptClocks, penaltyClocks, totalClocks int, logger *log.Logger) {
+	if logger != nil {
+		builder := strings.Builder{}
+
+		builder.WriteString(cpu.formatState())
+		builder.WriteString(" ; interrupt ")
+		builder.WriteString(strconv.Itoa(int(interruptType)))
+		writeClocks(&builder, interruptClocks, 0, penaltyClocks, totalClocks)
+		logger.Println(builder.String())
+	}
+}
+
+func (cpu *CPU) logHalt(idleClocks, totalClocks int, logger *log.Logger) {
+	if logger != nil {
+		builder := strings.Builder{}
+
+		builder.WriteString(cpu.formatState())
+		builder.WriteString(" ; halted")
+		writeClocks(&builder, idleClocks, 0, 0, totalClocks)
 		logger.Println(builder.String())
 	}
 }
 
+func writeClocks(builder *strings.Builder, instructionClocks, decodingClocks, penaltyClocks, totalClocks int) {
+	builder.WriteString(" +")
+	builder.WriteString(strconv.Itoa(instructionClocks + decodingClocks + penaltyClocks))
+	builder.WriteString(" = ")
+	builder.WriteString(strconv.Itoa(totalClocks))
+	if decodingClocks != 0 || penaltyClocks != 0 {
+		builder.WriteString(" (")
+		builder.WriteString(strconv.Itoa(instructionClocks))
+		if decodingClocks != 0 {
+			builder.WriteString(" + ")
+			builder.WriteString(strconv.Itoa(decodingClocks))
+			builder.WriteString("ea")
+		}
+		if penaltyClocks != 0 {
+			builder.WriteString(" + ")
+			builder.WriteString(strconv.Itoa(penaltyClocks))
+			builder.WriteString("p")
+		}
+		builder.WriteString(")")
+	}
+}
+
 // formatState formats the state in a loggable format.
-func formatState() string {
+func (cpu *CPU) formatState() string {
 	builder := strings.Builder{}
-	if TF != 0 {
+	if cpu.TF != 0 {
 		builder.WriteString("T")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if DF != 0 {
+	if cpu.DF != 0 {
 		builder.WriteString("D")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if IF != 0 {
+	if cpu.IF != 0 {
 		builder.WriteString("I")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if OF != 0 {
+	if cpu.OF != 0 {
 		builder.WriteString("O")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if SF != 0 {
+	if cpu.SF != 0 {
 		builder.WriteString("S")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if ZF != 0 {
+	if cpu.ZF != 0 {
 		builder.WriteString("Z")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if AF != 0 {
+	if cpu.AF != 0 {
 		builder.WriteString("A")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if PF != 0 {
+	if cpu.PF != 0 {
 		builder.WriteString("P")
 	} else {
 		builder.WriteByte(' ')
 	}
-	if CF != 0 {
+	if cpu.CF != 0 {
 		builder.WriteString("C")
 	} else {
 		builder.WriteByte(' ')
 	}
-	return fmt.Sprintf("AX:0x%04x BX:0x%04x CX:0x%04x DX:0x%04x SP:0x%04x BP:0x%04x SI:0x%04x DI:0x%04x IP:0x%04x CS:0x%04x DS:0x%04x SS:0x%04x ES:0x%04x F:%9s", AX, BX, CX, DX, SP, BP, SI, DI, IP, CS, DS, SS, ES, builder.String())
+	return fmt.Sprintf("AX:0x%04x BX:0x%04x CX:0x%04x DX:0x%04x SP:0x%04x BP:0x%04x SI:0x%04x DI:0x%04x IP:0x%04x CS:0x%04x DS:0x%04x SS:0x%04x ES:0x%04x F:%9s", cpu.AX, cpu.BX, cpu.CX, cpu.DX, cpu.SP, cpu.BP, cpu.SI, cpu.DI, cpu.IP, cpu.CS, cpu.DS, cpu.SS, cpu.ES, builder.String())
 }
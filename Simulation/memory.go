@@ -1,108 +1,125 @@
 package Simulation
 
-var AX uint16
-var BX uint16
-var CX uint16
-var DX uint16
-var SP uint16
-var BP uint16
-var SI uint16
-var DI uint16
-var IP uint16
-
-var CS = RESET_CS
-var DS uint16
-var SS uint16
-var ES uint16
-
-var TF byte
-var DF byte
-var IF byte
-var OF byte
-var SF byte
-var ZF byte
-var AF byte
-var PF byte
-var CF byte
-
-var Memory = [0xFFFFFF]byte{}
+// ADDRESS_SPACE_SIZE is the 1 MB the 20 address lines of the 8086 can reach
+const ADDRESS_SPACE_SIZE = 1 << 20
 
+// HIGH_MEMORY_SIZE is the size of the memory above 1 MB that FFFF:0010 to FFFF:FFFF reach, if the A20 gate is enabled
+const HIGH_MEMORY_SIZE = 0xFFF0
+
+// convertVirtualAddress converts a segmented address to a physical address, which wraps at 1 MB like on the 8086
 func convertVirtualAddress(segment uint16, offset uint16) int {
-	return (int(segment)<<4 + int(offset)) & 0xFFFFFF
+	return (int(segment)<<4 + int(offset)) & (ADDRESS_SPACE_SIZE - 1)
+}
+
+// physicalAddress converts a segmented address to a physical address according to the state of the A20 gate
+func (cpu *CPU) physicalAddress(segment uint16, offset uint16) int {
+	if cpu.A20Enabled {
+		return int(segment)<<4 + int(offset)
+	}
+	return convertVirtualAddress(segment, offset)
 }
 
-func read(segment, offset uint16, wide bool) uint16 {
+func (cpu *CPU) read(segment, offset uint16, wide bool) uint16 {
 	if wide {
-		return readW(segment, offset)
+		return cpu.readW(segment, offset)
 	}
-	return uint16(Memory[convertVirtualAddress(segment, offset)])
+	return uint16(cpu.readPhysical(cpu.physicalAddress(segment, offset)))
 }
 
-func readW(segment, offset uint16) uint16 {
-	return uint16(Memory[convertVirtualAddress(segment, offset)]) | uint16(Memory[convertVirtualAddress(segment, wrapIncrement(offset))])<<8
+func (cpu *CPU) readW(segment, offset uint16) uint16 {
+	return uint16(cpu.readPhysical(cpu.physicalAddress(segment, offset))) | uint16(cpu.readPhysical(cpu.physicalAddress(segment, wrapIncrement(offset))))<<8
 }
 
-func readCode(offset uint16, wide bool) uint16 {
+func (cpu *CPU) readCode(offset uint16, wide bool) uint16 {
 	if wide {
-		return readCodeW(offset)
+		return cpu.readCodeW(offset)
 	}
-	return uint16(readCodeB(offset))
+	return uint16(cpu.readCodeB(offset))
 }
 
-func readCodeB(offset uint16) byte {
-	return Memory[convertVirtualAddress(CS, offset)]
+// readCodeB reads a byte of the current instruction. Bytes are fetched from the memory bus only once,
+// so logging the instruction does not read them from memory-mapped devices again.
+func (cpu *CPU) readCodeB(offset uint16) byte {
+	index := int(offset - cpu.fetchStart)
+	if index < len(cpu.fetched) {
+		return cpu.fetched[index]
+	}
+	value := cpu.readPhysical(cpu.physicalAddress(cpu.CS, offset))
+	if index == len(cpu.fetched) {
+		cpu.fetched = append(cpu.fetched, value)
+	}
+	return value
 }
 
-func readCodeW(offset uint16) uint16 {
-	return uint16(readCodeB(offset)) | uint16(readCodeB(wrapIncrement(offset)))<<8
+// startFetch starts fetching a new instruction at offset
+func (cpu *CPU) startFetch(offset uint16) {
+	cpu.fetchStart = offset
+	cpu.fetched = cpu.fetched[:0]
 }
 
-func readData(offset uint16, wide bool) uint16 {
+func (cpu *CPU) readCodeW(offset uint16) uint16 {
+	return uint16(cpu.readCodeB(offset)) | uint16(cpu.readCodeB(wrapIncrement(offset)))<<8
+}
+
+func (cpu *CPU) readData(offset uint16, wide bool) uint16 {
 	if wide {
-		return readDataW(offset)
+		return cpu.readDataW(offset)
 	}
-	return uint16(readDataB(offset))
+	return uint16(cpu.readDataB(offset))
 }
 
-func readDataB(offset uint16) byte {
-	return Memory[convertVirtualAddress(DS, offset)]
+func (cpu *CPU) readDataB(offset uint16) byte {
+	return cpu.readPhysical(cpu.physicalAddress(cpu.applySegmentOverride(cpu.DS), offset))
 }
 
-func readDataW(offset uint16) uint16 {
-	return uint16(readDataB(offset)) | uint16(readDataB(wrapIncrement(offset)))<<8
+func (cpu *CPU) readDataW(offset uint16) uint16 {
+	return uint16(cpu.readDataB(offset)) | uint16(cpu.readDataB(wrapIncrement(offset)))<<8
 }
 
-func write(segment, offset, value uint16, wide bool) {
-	Memory[convertVirtualAddress(segment, offset)] = byte(value & uint16(_B_MAX))
+func (cpu *CPU) write(segment, offset, value uint16, wide bool) {
+	cpu.writePhysical(cpu.physicalAddress(segment, offset), byte(value&uint16(_B_MAX)))
 	if wide {
-		Memory[convertVirtualAddress(segment, wrapIncrement(offset))] = byte(value >> 8)
+		cpu.writePhysical(cpu.physicalAddress(segment, wrapIncrement(offset)), byte(value>>8))
 	}
 }
 
+// push decrements SP and writes value to the new top of the stack
+func (cpu *CPU) push(value uint16) {
+	cpu.SP = wrapSub(cpu.SP, 2)
+	cpu.write(cpu.SS, cpu.SP, value, true)
+}
+
+// pop reads the top of the stack and increments SP
+func (cpu *CPU) pop() uint16 {
+	value := cpu.readW(cpu.SS, cpu.SP)
+	cpu.SP = wrapAdd(cpu.SP, 2)
+	return value
+}
+
+// MemoryWriteError is returned if a program cannot be loaded or an instruction writes to read-only memory
 type MemoryWriteError string
 
 func (e MemoryWriteError) Error() string {
 	return "memory write error: " + string(e)
 }
 
-func LoadProgram(data []byte, isIncomplete bool) error {
-	if len(data) > len(Memory) {
+func (cpu *CPU) LoadProgram(data []byte, isIncomplete bool) error {
+	if len(data) > ADDRESS_SPACE_SIZE {
 		return MemoryWriteError("program too big")
 	}
-	copy(Memory[:], data)
+	cpu.loadPhysical(0, data)
 	if isIncomplete {
-		const RESET_VECTOR = int(RESET_CS) << 4
-		if len(data) < RESET_VECTOR-1 && data[len(data)-1] != 0b11110100 {
+		if len(data) < RESET_AREA_START-1 && data[len(data)-1] != 0b11110100 {
 			//HLT
-			Memory[len(data)] = 0b11110100
+			cpu.loadPhysical(len(data), []byte{0b11110100})
+		}
+		if len(data) < RESET_AREA_START {
+			//JMP 0:0
+			cpu.loadPhysical(RESET_AREA_START, []byte{0b11101010, 0, 0, 0, 0})
 		}
-		if len(data) < RESET_VECTOR {
-			//JMP
-			Memory[RESET_VECTOR] = 0b11101010
-			Memory[RESET_VECTOR+1] = 0
-			Memory[RESET_VECTOR+2] = 0
-			Memory[RESET_VECTOR+3] = 0
-			Memory[RESET_VECTOR+4] = 0
+		//the reset area contains BIOS code, which is read-only on hardware
+		if _, isReadOnly := cpu.getMemoryHandler(RESET_AREA_START).(readOnlyMemory); !isReadOnly {
+			cpu.MapROM(RESET_AREA_START, ADDRESS_SPACE_SIZE-1)
 		}
 	}
 	return nil
@@ -0,0 +1,77 @@
+package Simulation
+
+// MemoryHandler emulates a device on the memory bus like video RAM, an option ROM or a memory-mapped console.
+// Word accesses are split into two byte accesses, so a handler only sees bytes.
+type MemoryHandler interface {
+	// ReadMemory reads the byte at the physical address
+	ReadMemory(address int) byte
+	// WriteMemory writes a byte to the physical address
+	WriteMemory(address int, value byte)
+}
+
+type memoryMapping struct {
+	first, last int
+	handler     MemoryHandler
+}
+
+// memoryPageShift selects the 4 KB pages used to find unmapped addresses without searching the mappings
+const memoryPageShift = 12
+
+// MapMemory maps the physical addresses from first to last, inclusive, to handler.
+// Where mappings overlap, the one mapped last handles the access. Unmapped addresses are backed by Memory.
+// LoadProgram, LoadCOM and LoadEXE write through the handlers as well, except for ROM, which they fill directly.
+func (cpu *CPU) MapMemory(first, last int, handler MemoryHandler) {
+	cpu.memoryMappings = append(cpu.memoryMappings, memoryMapping{first: first, last: last, handler: handler})
+	for page := first >> memoryPageShift; page <= last>>memoryPageShift && page < len(cpu.mappedPages); page++ {
+		cpu.mappedPages[page] = true
+	}
+}
+
+// getMemoryHandler gets the handler responsible for address or nil if address is backed by Memory
+func (cpu *CPU) getMemoryHandler(address int) MemoryHandler {
+	if !cpu.mappedPages[address>>memoryPageShift] {
+		return nil
+	}
+	for i := len(cpu.memoryMappings) - 1; i >= 0; i-- {
+		if cpu.memoryMappings[i].first <= address && address <= cpu.memoryMappings[i].last {
+			return cpu.memoryMappings[i].handler
+		}
+	}
+	return nil
+}
+
+// readPhysical reads the byte at a physical address from the memory bus
+func (cpu *CPU) readPhysical(address int) byte {
+	if handler := cpu.getMemoryHandler(address); handler != nil {
+		return handler.ReadMemory(address)
+	}
+	return cpu.Memory[address]
+}
+
+// writePhysical writes a byte to a physical address on the memory bus
+func (cpu *CPU) writePhysical(address int, value byte) {
+	if handler := cpu.getMemoryHandler(address); handler != nil {
+		handler.WriteMemory(address, value)
+		return
+	}
+	cpu.Memory[address] = value
+}
+
+// readPhysicalBlock reads len(data) bytes starting at a physical address from the memory bus into data
+func (cpu *CPU) readPhysicalBlock(address int, data []byte) {
+	for i := range data {
+		data[i] = cpu.readPhysical(address + i)
+	}
+}
+
+// loadPhysical writes data starting at a physical address to the memory bus for the loaders.
+// Unlike instructions they can program ROM, so ROM regions are written to Memory.
+func (cpu *CPU) loadPhysical(address int, data []byte) {
+	for i, value := range data {
+		if _, isReadOnly := cpu.getMemoryHandler(address + i).(readOnlyMemory); isReadOnly {
+			cpu.Memory[address+i] = value
+			continue
+		}
+		cpu.writePhysical(address+i, value)
+	}
+}
@@ -0,0 +1,256 @@
+package Simulation
+
+// Ports of the 8259A programmable interrupt controller in a PC
+const (
+	PIC_COMMAND_PORT uint16 = 0x20
+	PIC_DATA_PORT    uint16 = 0x21
+)
+
+// pic initialization states
+const (
+	//an uninitialized 8259A does not request interrupts until ICW1 starts the initialization
+	picUninitialized byte = iota
+	picReady
+	picExpectICW2
+	picExpectICW3
+	picExpectICW4
+)
+
+// PIC emulates a single 8259A programmable interrupt controller in 8086 mode.
+// It supports the ICW1-ICW4 initialization sequence, masking with OCW1, EOI and priority rotation with OCW2
+// and reading IRR/ISR and polling with OCW3. Cascading and the special mask mode are not emulated.
+type PIC struct {
+	// irr, isr and imr are the interrupt request, in service and interrupt mask registers
+	irr, isr, imr byte
+	// lines contains the current level of the IRQ inputs
+	lines byte
+	// vectorBase is added to the IRQ number to get the interrupt type
+	vectorBase byte
+	// lowestPriority is the IRQ with the lowest priority, IRQ 7 without rotation
+	lowestPriority byte
+
+	initializationState byte
+	needsICW4           bool
+	single              bool
+	levelTriggered      bool
+	autoEOI             bool
+	rotateOnAutoEOI     bool
+	readISR             bool
+	poll                bool
+}
+
+// NewPIC creates an uninitialized 8259A
+func NewPIC() *PIC {
+	return &PIC{lowestPriority: 7}
+}
+
+// InstallPIC creates an 8259A, maps it to the PC ports 0x20 and 0x21 and connects it to the INTR pin
+func (cpu *CPU) InstallPIC() *PIC {
+	pic := NewPIC()
+	cpu.MapPorts(PIC_COMMAND_PORT, PIC_DATA_PORT, pic)
+	cpu.InterruptController = pic
+	return pic
+}
+
+// SetIRQ sets the level of an IRQ input. In edge triggered mode a rising edge requests an interrupt.
+// Like on the 8259A the request is withdrawn, if the input falls before the interrupt is acknowledged.
+func (pic *PIC) SetIRQ(irq byte, level bool) {
+	bit := byte(1) << (irq & 0b111)
+	if level {
+		if pic.lines&bit == 0 {
+			pic.irr |= bit
+		}
+		pic.lines |= bit
+	} else {
+		pic.lines &^= bit
+		pic.irr &^= bit
+	}
+}
+
+// requests gets the pending requests, which follow the inputs in level triggered mode
+func (pic *PIC) requests() byte {
+	if pic.levelTriggered {
+		return pic.lines
+	}
+	return pic.irr
+}
+
+// highestPriority gets the IRQ with the highest priority in set or false if set is empty
+func (pic *PIC) highestPriority(set byte) (byte, bool) {
+	for i := byte(1); i <= 8; i++ {
+		irq := (pic.lowestPriority + i) & 0b111
+		if set&(1<<irq) != 0 {
+			return irq, true
+		}
+	}
+	return 0, false
+}
+
+// pendingIRQ gets the unmasked request with the highest priority, if it has a higher priority than every IRQ in service
+func (pic *PIC) pendingIRQ() (byte, bool) {
+	if pic.initializationState != picReady {
+		return 0, false
+	}
+	irq, requested := pic.highestPriority(pic.requests() &^ pic.imr)
+	if !requested {
+		return 0, false
+	}
+	inService, serviced := pic.highestPriority(pic.isr)
+	//the priority of an IRQ is its distance from the IRQ with the lowest priority
+	if serviced && (inService-pic.lowestPriority-1)&0b111 <= (irq-pic.lowestPriority-1)&0b111 {
+		return 0, false
+	}
+	return irq, true
+}
+
+// InterruptRequested gets the level of the INTR pin
+func (pic *PIC) InterruptRequested() bool {
+	_, requested := pic.pendingIRQ()
+	return requested
+}
+
+// InterruptPossible reports if the PIC is initialized and at least one IRQ is unmasked
+func (pic *PIC) InterruptPossible() bool {
+	return pic.initializationState == picReady && pic.imr != _B_MAX
+}
+
+// AcknowledgeInterrupt moves the pending request with the highest priority in service and returns its interrupt type.
+// Like on the 8259A IRQ 7 is returned without being set in service, if the request was withdrawn.
+func (pic *PIC) AcknowledgeInterrupt() byte {
+	irq, requested := pic.pendingIRQ()
+	if !requested {
+		return pic.vectorBase | 7
+	}
+	pic.irr &^= 1 << irq
+	if pic.autoEOI {
+		if pic.rotateOnAutoEOI {
+			pic.lowestPriority = irq
+		}
+	} else {
+		pic.isr |= 1 << irq
+	}
+	return pic.vectorBase | irq
+}
+
+// In reads IRR, ISR or the poll result from the command port and IMR from the data port
+func (pic *PIC) In(port uint16, wide bool) uint16 {
+	if wide {
+		return pic.In(port, false) | pic.In(port+1, false)<<8
+	}
+	if port&1 != 0 {
+		return uint16(pic.imr)
+	}
+	if pic.poll {
+		pic.poll = false
+		irq, requested := pic.pendingIRQ()
+		if !requested {
+			return 0
+		}
+		pic.AcknowledgeInterrupt()
+		return 0b10000000 | uint16(irq)
+	}
+	if pic.readISR {
+		return uint16(pic.isr)
+	}
+	return uint16(pic.requests())
+}
+
+// Out writes ICW1, OCW2 or OCW3 to the command port and ICW2-ICW4 or OCW1 to the data port
+func (pic *PIC) Out(port, value uint16, wide bool) {
+	if wide {
+		pic.Out(port, value&_L, false)
+		pic.Out(port+1, readH(value), false)
+		return
+	}
+	data := byte(value)
+	if port&1 != 0 {
+		pic.writeData(data)
+		return
+	}
+	switch {
+	//ICW1
+	case data&0b00010000 != 0:
+		pic.irr = 0
+		pic.isr = 0
+		pic.imr = 0
+		pic.lowestPriority = 7
+		pic.readISR = false
+		pic.poll = false
+		pic.autoEOI = false
+		pic.rotateOnAutoEOI = false
+		pic.needsICW4 = data&0b00000001 != 0
+		pic.single = data&0b00000010 != 0
+		pic.levelTriggered = data&0b00001000 != 0
+		pic.initializationState = picExpectICW2
+	//OCW3
+	case data&0b00001000 != 0:
+		if data&0b00000010 != 0 {
+			pic.readISR = data&0b00000001 != 0
+		}
+		pic.poll = data&0b00000100 != 0
+	//OCW2
+	default:
+		pic.writeOCW2(data)
+	}
+}
+
+func (pic *PIC) writeData(data byte) {
+	switch pic.initializationState {
+	case picExpectICW2:
+		pic.vectorBase = data & 0b11111000
+		switch {
+		case !pic.single:
+			pic.initializationState = picExpectICW3
+		case pic.needsICW4:
+			pic.initializationState = picExpectICW4
+		default:
+			pic.initializationState = picReady
+		}
+	//the cascade configuration is not emulated
+	case picExpectICW3:
+		if pic.needsICW4 {
+			pic.initializationState = picExpectICW4
+		} else {
+			pic.initializationState = picReady
+		}
+	case picExpectICW4:
+		pic.autoEOI = data&0b00000010 != 0
+		pic.initializationState = picReady
+	//OCW1
+	default:
+		pic.imr = data
+	}
+}
+
+func (pic *PIC) writeOCW2(data byte) {
+	level := data & 0b111
+	switch data & 0b11100000 {
+	//rotate in automatic EOI mode clear
+	case 0b00000000:
+		pic.rotateOnAutoEOI = false
+	//rotate in automatic EOI mode set
+	case 0b10000000:
+		pic.rotateOnAutoEOI = true
+	//non-specific EOI
+	case 0b00100000:
+		if irq, serviced := pic.highestPriority(pic.isr); serviced {
+			pic.isr &^= 1 << irq
+		}
+	//rotate on non-specific EOI
+	case 0b10100000:
+		if irq, serviced := pic.highestPriority(pic.isr); serviced {
+			pic.isr &^= 1 << irq
+			pic.lowestPriority = irq
+		}
+	//specific EOI
+	case 0b01100000:
+		pic.isr &^= 1 << level
+	//rotate on specific EOI
+	case 0b11100000:
+		pic.isr &^= 1 << level
+		pic.lowestPriority = level
+	//set priority
+	case 0b11000000:
+		pic.lowestPriority = level
+	}
+}
@@ -0,0 +1,352 @@
+package Simulation
+
+// Ports of the 8253 programmable interval timer in a PC
+const (
+	PIT_CHANNEL_0_PORT uint16 = 0x40
+	PIT_CONTROL_PORT   uint16 = 0x43
+)
+
+// CPU_CYCLES_PER_PIT_CLOCK is the ratio of the 4.77 MHz CPU clock and the 1.193 MHz PIT clock in a PC
+const CPU_CYCLES_PER_PIT_CLOCK = 4
+
+// TIMER_IRQ is the IRQ channel 0 of the PIT is connected to in a PC
+const TIMER_IRQ byte = 0
+
+// pit counter access modes
+const (
+	pitLatch byte = iota
+	pitLSB
+	pitMSB
+	pitLSBThenMSB
+)
+
+type pitChannel struct {
+	mode       byte
+	accessMode byte
+	bcd        bool
+
+	// reload contains the last written count, count the counting element. Both are binary even in BCD mode.
+	reload, count int
+	// loaded is set once a count was written and cleared when the mode is programmed
+	loaded bool
+	// counting is set while the counting element follows the clock
+	counting bool
+	gate     bool
+	output   bool
+	// strobe is set while the output of mode 4 and 5 is low for one clock
+	strobe bool
+	// expired is set after the strobe of mode 4 and 5 until the next count or trigger
+	expired bool
+
+	latched     bool
+	latch       uint16
+	readHigh    bool
+	writeHigh   bool
+	pendingLow  byte
+	onOutputSet func(level bool)
+}
+
+// PIT emulates an 8253 programmable interval timer with three channels.
+// It supports all six counter modes, binary and BCD counting and counter latch commands.
+type PIT struct {
+	channels [3]pitChannel
+	// cycles contains the CPU cycles that did not add up to a complete PIT clock yet
+	cycles int
+}
+
+// NewPIT creates a PIT with all gates high and unprogrammed channels
+func NewPIT() *PIT {
+	pit := &PIT{}
+	for i := range pit.channels {
+		pit.channels[i].gate = true
+	}
+	return pit
+}
+
+// InstallPIT creates an 8253, maps it to the PC ports 0x40 to 0x43 and drives it with the CPU clock.
+// The output of channel 0 is connected to IRQ 0 of pic, if pic is not nil.
+func (cpu *CPU) InstallPIT(pic *PIC) *PIT {
+	pit := NewPIT()
+	cpu.MapPorts(PIT_CHANNEL_0_PORT, PIT_CONTROL_PORT, pit)
+	cpu.AttachClockedDevice(pit)
+	if pic != nil {
+		pit.ConnectOutput(0, func(level bool) {
+			pic.SetIRQ(TIMER_IRQ, level)
+		})
+	}
+	return pit
+}
+
+// ConnectOutput calls onOutputSet every time the output of channel changes
+func (pit *PIT) ConnectOutput(channel byte, onOutputSet func(level bool)) {
+	pit.channels[channel].onOutputSet = onOutputSet
+}
+
+// Output gets the level of the output of channel
+func (pit *PIT) Output(channel byte) bool {
+	return pit.channels[channel].output
+}
+
+// SetGate sets the level of the gate input of channel. Channel 0 and 1 are always enabled in a PC.
+func (pit *PIT) SetGate(channel byte, level bool) {
+	pit.channels[channel].setGate(level)
+}
+
+// Clock advances the PIT by the cycles of the CPU clock
+func (pit *PIT) Clock(cycles int) {
+	pit.cycles += cycles
+	for ; pit.cycles >= CPU_CYCLES_PER_PIT_CLOCK; pit.cycles -= CPU_CYCLES_PER_PIT_CLOCK {
+		for i := range pit.channels {
+			pit.channels[i].clock()
+		}
+	}
+}
+
+// In reads the count or latched count of a channel. The control port cannot be read.
+func (pit *PIT) In(port uint16, wide bool) uint16 {
+	if wide {
+		return pit.In(port, false) | pit.In(port+1, false)<<8
+	}
+	channel := port & 0b11
+	if channel == 0b11 {
+		return uint16(_B_MAX)
+	}
+	return uint16(pit.channels[channel].read())
+}
+
+// Out writes the count of a channel or a control word to the control port
+func (pit *PIT) Out(port, value uint16, wide bool) {
+	if wide {
+		pit.Out(port, value&_L, false)
+		pit.Out(port+1, readH(value), false)
+		return
+	}
+	channel := port & 0b11
+	if channel != 0b11 {
+		pit.channels[channel].write(byte(value))
+		return
+	}
+	controlWord := byte(value)
+	channel = uint16(controlWord >> 6)
+	//the read-back command only exists on the 8254
+	if channel == 0b11 {
+		return
+	}
+	pit.channels[channel].program(controlWord)
+}
+
+// period gets the number of counts of a complete cycle of the counting element
+func (channel *pitChannel) period() int {
+	if channel.bcd {
+		return 10000
+	}
+	return 65536
+}
+
+func (channel *pitChannel) setOutput(level bool) {
+	if channel.output == level {
+		return
+	}
+	channel.output = level
+	if channel.onOutputSet != nil {
+		channel.onOutputSet(level)
+	}
+}
+
+// value gets the counting element in the format of the counter
+func (channel *pitChannel) value() uint16 {
+	count := channel.count % channel.period()
+	if !channel.bcd {
+		return uint16(count)
+	}
+	return uint16(count/1000<<12 | count/100%10<<8 | count/10%10<<4 | count%10)
+}
+
+// program executes a control word for the channel
+func (channel *pitChannel) program(controlWord byte) {
+	accessMode := controlWord >> 4 & 0b11
+	if accessMode == pitLatch {
+		if !channel.latched {
+			channel.latched = true
+			channel.latch = channel.value()
+			channel.readHigh = false
+		}
+		return
+	}
+	channel.accessMode = accessMode
+	//modes 6 and 7 are aliases of 2 and 3
+	channel.mode = controlWord >> 1 & 0b111
+	if channel.mode > 5 {
+		channel.mode &= 0b011
+	}
+	channel.bcd = controlWord&1 != 0
+	channel.loaded = false
+	channel.counting = false
+	channel.strobe = false
+	channel.expired = false
+	channel.latched = false
+	channel.readHigh = false
+	channel.writeHigh = false
+	channel.setOutput(channel.mode != 0)
+}
+
+// write writes one byte of a count according to the access mode
+func (channel *pitChannel) write(data byte) {
+	var value uint16
+	switch channel.accessMode {
+	case pitLSB:
+		value = uint16(data)
+	case pitMSB:
+		value = uint16(data) << 8
+	case pitLSBThenMSB:
+		if !channel.writeHigh {
+			channel.writeHigh = true
+			channel.pendingLow = data
+			//like on the 8253 mode 0 stops counting until the count is complete
+			if channel.mode == 0 {
+				channel.counting = false
+			}
+			return
+		}
+		channel.writeHigh = false
+		value = uint16(data)<<8 | uint16(channel.pendingLow)
+	default:
+		return
+	}
+
+	channel.reload = int(value)
+	if channel.bcd {
+		channel.reload = int(value>>12&0xF)*1000 + int(value>>8&0xF)*100 + int(value>>4&0xF)*10 + int(value&0xF)
+	}
+	firstCount := !channel.loaded
+	channel.loaded = true
+
+	switch channel.mode {
+	case 0:
+		channel.count = channel.reload
+		channel.counting = true
+		channel.setOutput(false)
+	case 4:
+		channel.count = channel.reload
+		channel.counting = true
+		channel.expired = false
+	case 2, 3:
+		//new counts of running periodic modes are used for the next period
+		if firstCount {
+			channel.reloadPeriodic()
+			channel.counting = true
+		}
+	}
+	//modes 1 and 5 wait for a rising edge of the gate
+}
+
+// read reads one byte of the latched or current count according to the access mode
+func (channel *pitChannel) read() byte {
+	value := channel.value()
+	if channel.latched {
+		value = channel.latch
+	}
+	var data byte
+	switch channel.accessMode {
+	case pitLSB:
+		data = byte(value)
+		channel.latched = false
+	case pitMSB:
+		data = byte(value >> 8)
+		channel.latched = false
+	default:
+		if !channel.readHigh {
+			data = byte(value)
+			channel.readHigh = true
+		} else {
+			data = byte(value >> 8)
+			channel.readHigh = false
+			channel.latched = false
+		}
+	}
+	return data
+}
+
+// reloadPeriodic loads the count in mode 2 and 3. In mode 3 odd counts keep the output high one count longer than low.
+func (channel *pitChannel) reloadPeriodic() {
+	channel.count = channel.reload
+	if channel.mode == 3 && channel.reload&1 != 0 {
+		if channel.output {
+			channel.count++
+		} else {
+			channel.count--
+		}
+	}
+}
+
+func (channel *pitChannel) setGate(level bool) {
+	risingEdge := level && !channel.gate
+	channel.gate = level
+	switch channel.mode {
+	case 1, 5:
+		if risingEdge && channel.loaded {
+			channel.count = channel.reload
+			channel.counting = true
+			channel.strobe = false
+			channel.expired = false
+			if channel.mode == 1 {
+				channel.setOutput(false)
+			}
+		}
+	case 2, 3:
+		if !level {
+			channel.setOutput(true)
+		} else if risingEdge && channel.loaded {
+			channel.reloadPeriodic()
+			channel.counting = true
+		}
+	}
+}
+
+// clock advances the channel by one PIT clock
+func (channel *pitChannel) clock() {
+	if channel.strobe {
+		channel.strobe = false
+		channel.setOutput(true)
+	}
+	if !channel.counting {
+		return
+	}
+	//only the hardware triggered modes count while the gate is low
+	if !channel.gate && channel.mode != 1 && channel.mode != 5 {
+		return
+	}
+	period := channel.period()
+
+	switch channel.mode {
+	case 0, 1:
+		channel.count = (channel.count - 1 + period) % period
+		if channel.count == 0 {
+			channel.setOutput(true)
+		}
+	case 2:
+		if channel.count == 1 {
+			channel.reloadPeriodic()
+			channel.setOutput(true)
+			return
+		}
+		channel.count = (channel.count - 1 + period) % period
+		if channel.count == 1 {
+			channel.setOutput(false)
+		}
+	case 3:
+		channel.count = (channel.count - 2 + period) % period
+		if channel.count == 0 {
+			channel.setOutput(!channel.output)
+			channel.reloadPeriodic()
+		}
+	case 4, 5:
+		channel.count = (channel.count - 1 + period) % period
+		//the strobe is only generated once per trigger, afterwards the counter wraps around
+		if channel.count == 0 && !channel.expired {
+			channel.expired = true
+			channel.strobe = true
+			channel.setOutput(false)
+		}
+	}
+}
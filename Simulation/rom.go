@@ -0,0 +1,47 @@
+package Simulation
+
+import "fmt"
+
+// ReadOnlyWriteBehaviour defines how writes to read-only memory are handled
+type ReadOnlyWriteBehaviour byte
+
+const (
+	// READ_ONLY_WRITES_IGNORED ignores writes to read-only memory like the hardware
+	READ_ONLY_WRITES_IGNORED ReadOnlyWriteBehaviour = iota
+	// READ_ONLY_WRITES_FAIL stops the simulation with a MemoryWriteError after the offending instruction
+	READ_ONLY_WRITES_FAIL
+)
+
+// RESET_AREA_START is the physical address of the last 16 bytes of the address space, where the 8086 starts after a reset
+const RESET_AREA_START = int(RESET_CS) << 4
+
+// readOnlyMemory is a memory handler for ROM regions, which are backed by Memory
+type readOnlyMemory struct {
+	cpu *CPU
+}
+
+func (rom readOnlyMemory) ReadMemory(address int) byte {
+	return rom.cpu.Memory[address]
+}
+
+func (rom readOnlyMemory) WriteMemory(address int, _ byte) {
+	if rom.cpu.ReadOnlyWrites == READ_ONLY_WRITES_FAIL && !rom.cpu.readOnlyWritten {
+		rom.cpu.readOnlyWritten = true
+		rom.cpu.readOnlyWriteAddress = address
+	}
+}
+
+// MapROM makes the physical addresses from first to last, inclusive, read-only.
+// The content of the ROM is taken from Memory, so it can be loaded before or after mapping it.
+func (cpu *CPU) MapROM(first, last int) {
+	cpu.MapMemory(first, last, readOnlyMemory{cpu: cpu})
+}
+
+// checkReadOnlyWrites gets a MemoryWriteError, if the instruction at segment:offset wrote to read-only memory
+func (cpu *CPU) checkReadOnlyWrites(segment, offset uint16) error {
+	if !cpu.readOnlyWritten {
+		return nil
+	}
+	cpu.readOnlyWritten = false
+	return MemoryWriteError(fmt.Sprintf("write to read-only address 0x%05x by instruction at %04x:%04x", cpu.readOnlyWriteAddress, segment, offset))
+}
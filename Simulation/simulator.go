@@ -26,10 +26,18 @@ func newInvalidParameterErrorInvalidInstruction(segment, offset uint16) *Decodin
 	return newInvalidParameterError(segment, offset, "invalid instruction in register portion")
 }
 
+func newUndocumentedInstructionError(segment, offset uint16) *DecodingError {
+	return &DecodingError{Message: "undocumented instruction", Pos: convertVirtualAddress(segment, offset)}
+}
+
 func newUnsupportedError(segment, offset uint16, reason string) *DecodingError {
 	return &DecodingError{Message: "unsupported function (" + reason + ")", Pos: convertVirtualAddress(segment, offset)}
 }
 
+func newHaltTimeoutError(segment, offset uint16) *DecodingError {
+	return &DecodingError{Message: "no interrupt within " + strconv.Itoa(MAX_HALT_CYCLES) + " cycles after HLT", Pos: convertVirtualAddress(segment, offset)}
+}
+
 //endregion
 
 // Simulate reads instruction stream and simulates execution
@@ -37,14 +45,40 @@ func newUnsupportedError(segment, offset uint16, reason string) *DecodingError {
 //   - invalid instruction
 //   - invalid parameters
 //   - instruction stream stops before complete decoding of instruction
+//   - write to read-only memory, if configured
+//   - no interrupt within MAX_HALT_CYCLES after HLT
+//
+// The simulation ends at HLT, unless an interrupt can still wake the CPU.
 //
 //goland:noinspection SpellCheckingInspection
-func Simulate(logger *log.Logger) error {
-	var startOfInstruction = IP
+func (cpu *CPU) Simulate(logger *log.Logger) error {
+	var startOfInstruction = cpu.IP
 	var baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles int
+	var prefixCycles int
+	var repeatInProgress bool
+	var repeatResumeIP uint16
 
 	for {
-		currentInstructionByte := readCodeB(IP)
+		var instruction []byte
+		//interrupts are not recognized directly after SS is loaded, so SS:SP can be changed without interruption
+		var inhibitInterrupts bool
+		//the single step trap follows instructions that started with TF set
+		trap := cpu.TF != 0
+		//HLT waits for an interrupt, if one can still arrive
+		var halted bool
+		//CS of the current instruction to report write protection violations
+		codeSegment := cpu.CS
+		//prefixes belong to the instruction they precede
+		if cpu.IP == startOfInstruction {
+			cpu.startFetch(cpu.IP)
+		}
+		currentInstructionByte := cpu.readCodeB(cpu.IP)
+		if Shared.IsUndocumentedOpcode(currentInstructionByte) {
+			if cpu.OpcodeMode == Shared.STRICT_OPCODES {
+				return newUndocumentedInstructionError(cpu.CS, cpu.IP)
+			}
+			currentInstructionByte = Shared.GetDocumentedOpcode(currentInstructionByte)
+		}
 
 		switch currentInstructionByte {
 
@@ -53,86 +87,83 @@ func Simulate(logger *log.Logger) error {
 			fallthrough
 		case 0b10001001:
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
-			sourceValue := readRegister(wide | parameter&Shared.RegMask>>3)
-			segment, offset := calculateSegmentAndDisplacementByParameter(parameter, IP)
-			writeRMValue(parameter, segment, offset, sourceValue, wide)
-			IP = incrementIPByParameter(IP, parameter)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			sourceValue := cpu.readRegister(wide | parameter&Shared.RegMask>>3)
+			segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.writeRMValue(parameter, segment, offset, sourceValue, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, true, 2, 8, 9)
 		case 0b10001010:
 			fallthrough
 		case 0b10001011:
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
-			sourceValue, _, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, wide)
-			writeRegister(wide|parameter&Shared.RegMask>>3, sourceValue)
-			IP = incrementIPByParameter(IP, parameter)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			sourceValue, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.writeRegister(wide|parameter&Shared.RegMask>>3, sourceValue)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, 2, 8, 9)
 		//MOV segment register to R/M
 		case 0b10001100:
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
 			var sourceValue uint16
 			switch parameter & Shared.RegMask {
 			case 0b000000:
-				sourceValue = ES
+				sourceValue = cpu.ES
 			case 0b001000:
-				sourceValue = CS
+				sourceValue = cpu.CS
 			case 0b010000:
-				sourceValue = SS
+				sourceValue = cpu.SS
 			case 0b011000:
-				sourceValue = DS
+				sourceValue = cpu.DS
 			default:
-				return newInvalidParameterErrorInvalidInstruction(CS, IP)
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
 			}
-			segment, offset := calculateSegmentAndDisplacementByParameter(parameter, IP)
-			writeRMValue(parameter, segment, offset, sourceValue, Shared.WIDE)
-			IP = incrementIPByParameter(IP, parameter)
+			segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.writeRMValue(parameter, segment, offset, sourceValue, Shared.WIDE)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, true, true, 2, 0, 8)
 		//MOV R/M to segment register
 		case 0b10001110:
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
-			sourceValue, _, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, Shared.WIDE)
-			IP = incrementIPByParameter(IP, parameter)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			sourceValue, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, Shared.WIDE)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 2, 9, 0)
 			switch parameter & Shared.RegMask {
 			case 0b000000:
-				ES = sourceValue
+				cpu.ES = sourceValue
 			case 0b001000:
-				instruction := readInstruction(startOfInstruction, IP)
-				CS = sourceValue
-				IP = wrapIncrement(IP)
-				totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-				logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-				startOfInstruction = IP
-				continue
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.CS = sourceValue
+				cpu.IP = wrapIncrement(cpu.IP)
 			case 0b010000:
-				SS = sourceValue
+				cpu.SS = sourceValue
+				inhibitInterrupts = true
 			case 0b011000:
-				DS = sourceValue
+				cpu.DS = sourceValue
 			default:
-				return newInvalidParameterErrorInvalidInstruction(CS, IP)
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
 			}
 		//MOV immediate
 		case 0b11000110:
 			fallthrough
 		case 0b11000111:
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
 			if parameter&Shared.RegMask != 0 {
-				return newInvalidParameterErrorInvalidInstruction(CS, IP)
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
 			}
-			segment, offset := calculateSegmentAndDisplacementByParameter(parameter, IP)
-			IP = wrapIncrement(incrementIPByParameter(IP, parameter))
-			immediate := readCode(IP, wide != 0)
+			segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.IP = wrapIncrement(incrementIPByParameter(cpu.IP, parameter))
+			immediate := cpu.readCode(cpu.IP, wide != 0)
 			if wide != 0 {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
-			writeRMValue(parameter, segment, offset, immediate, wide)
+			cpu.writeRMValue(parameter, segment, offset, immediate, wide)
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, true, 4, 0, 10)
 		//MOV immediate into register
 		case 0b10110000:
@@ -167,14 +198,105 @@ func Simulate(logger *log.Logger) error {
 			fallthrough
 		case 0b10111111:
 			register := currentInstructionByte & 0b00001111
-			IP = wrapIncrement(IP)
-			sourceValue := readCode(IP, register > 0b0111)
+			cpu.IP = wrapIncrement(cpu.IP)
+			sourceValue := cpu.readCode(cpu.IP, register > 0b0111)
 			if register > 0b0111 {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
-			writeRegister(register, sourceValue)
+			cpu.writeRegister(register, sourceValue)
 			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
 
+		//PUSH register
+		case 0b01010000:
+			fallthrough
+		case 0b01010001:
+			fallthrough
+		case 0b01010010:
+			fallthrough
+		case 0b01010011:
+			fallthrough
+		case 0b01010100:
+			fallthrough
+		case 0b01010101:
+			fallthrough
+		case 0b01010110:
+			fallthrough
+		case 0b01010111:
+			//SP is decremented before the register is read, so PUSH SP pushes the decremented value
+			cpu.SP = wrapSub(cpu.SP, 2)
+			cpu.write(cpu.SS, cpu.SP, cpu.readRegister(currentInstructionByte&Shared.RMMask|Shared.WIDE), true)
+			baseClockCycles, decodingCycles, penaltyCycles = 11, 0, getWordTransferPenaltyCycles(cpu.SP)
+		//PUSH segment register
+		case 0b00000110:
+			fallthrough
+		case 0b00001110:
+			fallthrough
+		case 0b00010110:
+			fallthrough
+		case 0b00011110:
+			cpu.push(cpu.readSegmentRegister(currentInstructionByte & Shared.SegMask >> 3))
+			baseClockCycles, decodingCycles, penaltyCycles = 10, 0, getWordTransferPenaltyCycles(cpu.SP)
+		//PUSHF
+		case 0b10011100:
+			cpu.push(cpu.Flags())
+			baseClockCycles, decodingCycles, penaltyCycles = 10, 0, getWordTransferPenaltyCycles(cpu.SP)
+
+		//POP register
+		case 0b01011000:
+			fallthrough
+		case 0b01011001:
+			fallthrough
+		case 0b01011010:
+			fallthrough
+		case 0b01011011:
+			fallthrough
+		case 0b01011100:
+			fallthrough
+		case 0b01011101:
+			fallthrough
+		case 0b01011110:
+			fallthrough
+		case 0b01011111:
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			cpu.writeRegister(currentInstructionByte&Shared.RMMask|Shared.WIDE, cpu.pop())
+			baseClockCycles, decodingCycles = 8, 0
+		//POP segment register
+		case 0b00000111:
+			fallthrough
+		case 0b00010111:
+			fallthrough
+		case 0b00011111:
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			cpu.writeSegmentRegister(currentInstructionByte&Shared.SegMask>>3, cpu.pop())
+			inhibitInterrupts = currentInstructionByte == 0b00010111
+			baseClockCycles, decodingCycles = 8, 0
+		//POP CS, which is only executed in the silicon opcode mode
+		case 0b00001111:
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			cpu.CS = cpu.pop()
+			baseClockCycles, decodingCycles = 8, 0
+		//POP R/M
+		case 0b10001111:
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			if parameter&Shared.RegMask != 0 {
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
+			}
+			stackPenaltyCycles := getWordTransferPenaltyCycles(cpu.SP)
+			value := cpu.pop()
+			segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.writeRMValue(parameter, segment, offset, value, Shared.WIDE)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, true, true, 8, 0, 17)
+			penaltyCycles += stackPenaltyCycles
+		//POPF
+		case 0b10011101:
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			cpu.SetFlags(cpu.pop())
+			baseClockCycles, decodingCycles = 8, 0
+
 		//ADD/OR/ADC/SUB/AND/SBB/CMP immediate to R/M
 		case 0b10000000:
 			fallthrough
@@ -185,13 +307,13 @@ func Simulate(logger *log.Logger) error {
 		case 0b10000011:
 			signExtended := currentInstructionByte&Shared.DirectionMask != 0
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
-			sourceValue, segment, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, wide)
-			IP = wrapIncrement(incrementIPByParameter(IP, parameter))
-			immediate := readCode(IP, wide != 0 && !signExtended)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			sourceValue, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = wrapIncrement(incrementIPByParameter(cpu.IP, parameter))
+			immediate := cpu.readCode(cpu.IP, wide != 0 && !signExtended)
 			if wide != 0 && !signExtended {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
 			if signExtended {
 				immediate = signExtend(immediate)
@@ -201,19 +323,30 @@ func Simulate(logger *log.Logger) error {
 			//var name = [8]string{"ADD ", "OR ", "ADC ", "SBB ", "AND ", "SUB ", "XOR ", "CMP "}[parameter&Shared.RegMask>>3]
 			switch parameter & Shared.RegMask {
 			case 0b000000:
-				result = addAndUpdateFlags(sourceValue, immediate, wide != 0)
+				result = cpu.addAndUpdateFlags(sourceValue, immediate, wide != 0)
+			case 0b001000:
+				result = cpu.orAndUpdateFlags(sourceValue, immediate, wide != 0)
+			case 0b010000:
+				result = cpu.addWithCarryAndUpdateFlags(sourceValue, immediate, cpu.CF, wide != 0)
+			case 0b011000:
+				result = cpu.subWithBorrowAndUpdateFlags(sourceValue, immediate, cpu.CF, wide != 0)
+			case 0b100000:
+				result = cpu.andAndUpdateFlags(sourceValue, immediate, wide != 0)
+			case 0b110000:
+				result = cpu.xorAndUpdateFlags(sourceValue, immediate, wide != 0)
 			case 0b101000:
-				result = subAndUpateFlags(sourceValue, immediate, wide != 0)
+				result = cpu.subAndUpateFlags(sourceValue, immediate, wide != 0)
 			case 0b111000:
-				_ = subAndUpateFlags(sourceValue, immediate, wide != 0)
+				_ = cpu.subAndUpateFlags(sourceValue, immediate, wide != 0)
 				memoryCycles = 10
-			default:
-				return newUnsupportedError(CS, IP, "operation not implemented")
 			}
-			writeRMValue(parameter, segment, offset, result, wide)
+			//CMP only reads its operand
+			if parameter&Shared.RegMask != 0b111000 {
+				cpu.writeRMValue(parameter, segment, offset, result, wide)
+			}
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 4, 0, memoryCycles)
 
-		//ADD register with R/M
+		//ADD/ADC register with R/M
 		case 0b00000000:
 			fallthrough
 		case 0b00000001:
@@ -221,40 +354,68 @@ func Simulate(logger *log.Logger) error {
 		case 0b00000010:
 			fallthrough
 		case 0b00000011:
+			fallthrough
+		case 0b00010000:
+			fallthrough
+		case 0b00010001:
+			fallthrough
+		case 0b00010010:
+			fallthrough
+		case 0b00010011:
+			var carry byte
+			if currentInstructionByte&0b00010000 != 0 {
+				carry = cpu.CF
+			}
 			sourceInReg := currentInstructionByte&Shared.DirectionMask == 0
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
 			reg := wide | (parameter & Shared.RegMask >> 3)
-			regValue := readRegister(reg)
-			rmValue, segment, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, wide)
-			IP = incrementIPByParameter(IP, parameter)
-			sum := addAndUpdateFlags(regValue, rmValue, wide != 0)
+			regValue := cpu.readRegister(reg)
+			rmValue, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			sum := cpu.addWithCarryAndUpdateFlags(regValue, rmValue, carry, wide != 0)
 			if sourceInReg {
-				writeRMValue(parameter, segment, offset, sum, wide)
+				cpu.writeRMValue(parameter, segment, offset, sum, wide)
 			} else {
-				writeRegister(reg, sum)
+				cpu.writeRegister(reg, sum)
 			}
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, sourceInReg, wide != 0, false, 3, 9, 16)
-		//ADD immediate to accumulator
+		//ADD/ADC immediate to accumulator
 		case 0b00000100:
 			fallthrough
 		case 0b00000101:
+			fallthrough
+		case 0b00010100:
+			fallthrough
+		case 0b00010101:
+			var carry byte
+			if currentInstructionByte&0b00010000 != 0 {
+				carry = cpu.CF
+			}
 			wide := currentInstructionByte&Shared.WideMask != 0
-			IP = wrapIncrement(IP)
-			sourceValue := readCode(IP, wide)
+			cpu.IP = wrapIncrement(cpu.IP)
+			sourceValue := cpu.readCode(cpu.IP, wide)
 			if wide {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
-			sum := addAndUpdateFlags(AX, sourceValue, wide)
+			sum := cpu.addWithCarryAndUpdateFlags(cpu.AX, sourceValue, carry, wide)
 			if wide {
-				AX = sum
+				cpu.AX = sum
 			} else {
-				AX = writeL(AX, sum)
+				cpu.AX = writeL(cpu.AX, sum)
 			}
 			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
 
-		//SUB register and R/M
+		//SUB/SBB register and R/M
+		case 0b00011000:
+			fallthrough
+		case 0b00011001:
+			fallthrough
+		case 0b00011010:
+			fallthrough
+		case 0b00011011:
+			fallthrough
 		case 0b00101000:
 			fallthrough
 		case 0b00101001:
@@ -262,38 +423,89 @@ func Simulate(logger *log.Logger) error {
 		case 0b00101010:
 			fallthrough
 		case 0b00101011:
+			var borrow byte
+			if currentInstructionByte&0b00010000 != 0 {
+				borrow = cpu.CF
+			}
 			sourceInReg := currentInstructionByte&Shared.DirectionMask == 0
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
 			reg := wide | (parameter & Shared.RegMask >> 3)
-			regValue := readRegister(reg)
-			rmValue, segment, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, wide)
-			IP = incrementIPByParameter(IP, parameter)
+			regValue := cpu.readRegister(reg)
+			rmValue, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			if sourceInReg {
-				writeRMValue(parameter, segment, offset, subAndUpateFlags(rmValue, regValue, wide != 0), wide)
+				cpu.writeRMValue(parameter, segment, offset, cpu.subWithBorrowAndUpdateFlags(rmValue, regValue, borrow, wide != 0), wide)
 			} else {
-				writeRegister(reg, subAndUpateFlags(regValue, rmValue, wide != 0))
+				cpu.writeRegister(reg, cpu.subWithBorrowAndUpdateFlags(regValue, rmValue, borrow, wide != 0))
 			}
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, sourceInReg, wide != 0, false, 3, 9, 16)
-		//SUB immediate from accumulator
+		//SUB/SBB immediate from accumulator
+		case 0b00011100:
+			fallthrough
+		case 0b00011101:
+			fallthrough
 		case 0b00101100:
 			fallthrough
 		case 0b00101101:
+			var borrow byte
+			if currentInstructionByte&0b00010000 != 0 {
+				borrow = cpu.CF
+			}
 			wide := currentInstructionByte&Shared.WideMask != 0
-			IP = wrapIncrement(IP)
-			sourceValue := readCode(IP, wide)
+			cpu.IP = wrapIncrement(cpu.IP)
+			sourceValue := cpu.readCode(cpu.IP, wide)
 			if wide {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
-			difference := subAndUpateFlags(AX, sourceValue, wide)
+			difference := cpu.subWithBorrowAndUpdateFlags(cpu.AX, sourceValue, borrow, wide)
 			if wide {
-				AX = difference
+				cpu.AX = difference
 			} else {
-				AX = writeL(AX, difference)
+				cpu.AX = writeL(cpu.AX, difference)
 			}
 			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
 
+		//INC register
+		case 0b01000000:
+			fallthrough
+		case 0b01000001:
+			fallthrough
+		case 0b01000010:
+			fallthrough
+		case 0b01000011:
+			fallthrough
+		case 0b01000100:
+			fallthrough
+		case 0b01000101:
+			fallthrough
+		case 0b01000110:
+			fallthrough
+		case 0b01000111:
+			register := currentInstructionByte&Shared.RMMask | Shared.WIDE
+			cpu.writeRegister(register, cpu.incAndUpdateFlags(cpu.readRegister(register), true))
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//DEC register
+		case 0b01001000:
+			fallthrough
+		case 0b01001001:
+			fallthrough
+		case 0b01001010:
+			fallthrough
+		case 0b01001011:
+			fallthrough
+		case 0b01001100:
+			fallthrough
+		case 0b01001101:
+			fallthrough
+		case 0b01001110:
+			fallthrough
+		case 0b01001111:
+			register := currentInstructionByte&Shared.RMMask | Shared.WIDE
+			cpu.writeRegister(register, cpu.decAndUpdateFlags(cpu.readRegister(register), true))
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+
 		//CMP register to R/M
 		case 0b00111000:
 			fallthrough
@@ -304,14 +516,15 @@ func Simulate(logger *log.Logger) error {
 		case 0b00111011:
 			sourceInReg := currentInstructionByte&Shared.DirectionMask == 0
 			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
-			IP = wrapIncrement(IP)
-			parameter := readCodeB(IP)
-			regValue := readRegister(wide | (parameter & Shared.RegMask >> 3))
-			rmValue, _, offset := readRMValueSegmentAndDisplacementByParameter(parameter, IP, wide)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			regValue := cpu.readRegister(wide | (parameter & Shared.RegMask >> 3))
+			rmValue, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
 			if sourceInReg {
-				_ = subAndUpateFlags(rmValue, regValue, wide != 0)
+				_ = cpu.subAndUpateFlags(rmValue, regValue, wide != 0)
 			} else {
-				_ = subAndUpateFlags(regValue, rmValue, wide != 0)
+				_ = cpu.subAndUpateFlags(regValue, rmValue, wide != 0)
 			}
 			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, sourceInReg, wide != 0, true, 3, 9, 9)
 		//CMP immediate with accumulator
@@ -319,50 +532,290 @@ func Simulate(logger *log.Logger) error {
 			fallthrough
 		case 0b00111101:
 			wide := currentInstructionByte&Shared.WideMask != 0
-			IP = wrapIncrement(IP)
-			sourceValue := readCode(IP, wide)
+			cpu.IP = wrapIncrement(cpu.IP)
+			sourceValue := cpu.readCode(cpu.IP, wide)
 			if wide {
-				IP = wrapIncrement(IP)
+				cpu.IP = wrapIncrement(cpu.IP)
 			}
-			_ = subAndUpateFlags(AX, sourceValue, wide)
+			_ = cpu.subAndUpateFlags(cpu.AX, sourceValue, wide)
 			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
 
+		//OR/AND/XOR register with R/M
+		case 0b00001000:
+			fallthrough
+		case 0b00001001:
+			fallthrough
+		case 0b00001010:
+			fallthrough
+		case 0b00001011:
+			fallthrough
+		case 0b00100000:
+			fallthrough
+		case 0b00100001:
+			fallthrough
+		case 0b00100010:
+			fallthrough
+		case 0b00100011:
+			fallthrough
+		case 0b00110000:
+			fallthrough
+		case 0b00110001:
+			fallthrough
+		case 0b00110010:
+			fallthrough
+		case 0b00110011:
+			sourceInReg := currentInstructionByte&Shared.DirectionMask == 0
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			reg := wide | (parameter & Shared.RegMask >> 3)
+			regValue := cpu.readRegister(reg)
+			rmValue, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			var result uint16
+			switch currentInstructionByte & 0b00111000 {
+			case 0b001000:
+				result = cpu.orAndUpdateFlags(regValue, rmValue, wide != 0)
+			case 0b100000:
+				result = cpu.andAndUpdateFlags(regValue, rmValue, wide != 0)
+			case 0b110000:
+				result = cpu.xorAndUpdateFlags(regValue, rmValue, wide != 0)
+			}
+			if sourceInReg {
+				cpu.writeRMValue(parameter, segment, offset, result, wide)
+			} else {
+				cpu.writeRegister(reg, result)
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, sourceInReg, wide != 0, false, 3, 9, 16)
+		//OR/AND/XOR/TEST immediate with accumulator
+		case 0b00001100:
+			fallthrough
+		case 0b00001101:
+			fallthrough
+		case 0b00100100:
+			fallthrough
+		case 0b00100101:
+			fallthrough
+		case 0b00110100:
+			fallthrough
+		case 0b00110101:
+			fallthrough
+		case 0b10101000:
+			fallthrough
+		case 0b10101001:
+			wide := currentInstructionByte&Shared.WideMask != 0
+			cpu.IP = wrapIncrement(cpu.IP)
+			sourceValue := cpu.readCode(cpu.IP, wide)
+			if wide {
+				cpu.IP = wrapIncrement(cpu.IP)
+			}
+			var result uint16
+			switch currentInstructionByte {
+			case 0b00001100:
+				fallthrough
+			case 0b00001101:
+				result = cpu.orAndUpdateFlags(cpu.AX, sourceValue, wide)
+			case 0b00100100:
+				fallthrough
+			case 0b00100101:
+				result = cpu.andAndUpdateFlags(cpu.AX, sourceValue, wide)
+			case 0b00110100:
+				fallthrough
+			case 0b00110101:
+				result = cpu.xorAndUpdateFlags(cpu.AX, sourceValue, wide)
+			default:
+				_ = cpu.andAndUpdateFlags(cpu.AX, sourceValue, wide)
+				result = cpu.AX
+			}
+			if wide {
+				cpu.AX = result
+			} else {
+				cpu.AX = writeL(cpu.AX, result)
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+		//TEST register and R/M
+		case 0b10000100:
+			fallthrough
+		case 0b10000101:
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			regValue := cpu.readRegister(wide | (parameter & Shared.RegMask >> 3))
+			rmValue, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			_ = cpu.andAndUpdateFlags(regValue, rmValue, wide != 0)
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, 3, 9, 0)
+
+		//TEST/NOT/NEG/MUL/IMUL/DIV/IDIV R/M
+		case 0b11110110:
+			fallthrough
+		case 0b11110111:
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			if parameter&Shared.RegMask == 0b001000 {
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
+			}
+			value, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			switch parameter & Shared.RegMask {
+			//TEST immediate
+			case 0b000000:
+				cpu.IP = wrapIncrement(cpu.IP)
+				immediate := cpu.readCode(cpu.IP, wide != 0)
+				if wide != 0 {
+					cpu.IP = wrapIncrement(cpu.IP)
+				}
+				_ = cpu.andAndUpdateFlags(value, immediate, wide != 0)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, 5, 11, 0)
+			//NOT
+			case 0b010000:
+				cpu.writeRMValue(parameter, segment, offset, ^value, wide)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 3, 0, 16)
+			//NEG
+			case 0b011000:
+				cpu.writeRMValue(parameter, segment, offset, cpu.subAndUpateFlags(0, value, wide != 0), wide)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 3, 0, 16)
+			//MUL
+			case 0b100000:
+				cpu.multiplyAndUpdateFlags(value, false, wide != 0)
+				cycles := [2][2]int{{70, 77}, {118, 133}}[wide>>3]
+				cycles[0] = getDataDependentCycles(cycles[0], cycles[1], value, wide != 0)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, cycles[0], cycles[0]+6, 0)
+			//IMUL
+			case 0b101000:
+				cpu.multiplyAndUpdateFlags(value, true, wide != 0)
+				cycles := [2][2]int{{80, 98}, {128, 154}}[wide>>3]
+				cycles[0] = getDataDependentCycles(cycles[0], cycles[1], getAbsoluteValue(value, wide != 0), wide != 0)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, cycles[0], cycles[0]+6, 0)
+			//DIV/IDIV
+			case 0b110000:
+				fallthrough
+			case 0b111000:
+				signed := parameter&Shared.RegMask == 0b111000
+				cycles := [2][2][2]int{{{80, 90}, {144, 162}}, {{101, 112}, {165, 184}}}[parameter>>3&1][wide>>3]
+				quotient, valid := cpu.divideAndUpdateFlags(value, signed, wide != 0)
+				if signed {
+					quotient = getAbsoluteValue(quotient, wide != 0)
+				}
+				cycles[0] = getDataDependentCycles(cycles[0], cycles[1], quotient, wide != 0)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, wide != 0, true, cycles[0], cycles[0]+6, 0)
+				if !valid {
+					//the 8086 pushes the address of the next instruction for divide errors
+					instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+					cpu.IP = wrapIncrement(cpu.IP)
+					penaltyCycles += 3 * getWordTransferPenaltyCycles(cpu.SP)
+					cpu.interrupt(DIVIDE_ERROR_INTERRUPT)
+					baseClockCycles += 51
+				}
+			}
+
+		//ROL/ROR/RCL/RCR/SHL/SAL/SHR/SAR
+		case 0b11010000:
+			fallthrough
+		case 0b11010001:
+			fallthrough
+		case 0b11010010:
+			fallthrough
+		case 0b11010011:
+			countInCL := currentInstructionByte&Shared.DirectionMask != 0
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			value, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			var count byte = 1
+			registerCycles, memoryCycles := 2, 15
+			if countInCL {
+				count = byte(cpu.CX & _L)
+				registerCycles, memoryCycles = 8+4*int(count), 20+4*int(count)
+			}
+			//a count of 0 leaves the operand untouched
+			if count != 0 {
+				cpu.writeRMValue(parameter, segment, offset, cpu.shiftAndUpdateFlags(parameter&Shared.RegMask>>3, value, count, wide != 0), wide)
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, registerCycles, 0, memoryCycles)
+
 		//JMP
 		case 0b11101001:
-			IP = wrapIncrement(IP)
-			offset := readCodeW(IP)
-			IP = wrapAdd(IP, 2)
-			instruction := readInstruction(startOfInstruction, IP)
-			IP = calculateJump(offset, IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			offset := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = calculateJump(offset, cpu.IP)
 			baseClockCycles, decodingCycles, penaltyCycles = 15, 0, 0
-			totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-			logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-			startOfInstruction = IP
-			continue
 		case 0b11101010:
-			IP = wrapIncrement(IP)
-			newIP := readCodeW(IP)
-			IP = wrapAdd(IP, 2)
-			newCS := readCodeW(IP)
-			IP = wrapAdd(IP, 1)
-			instruction := readInstruction(startOfInstruction, IP)
-			CS = newCS
-			IP = newIP
+			cpu.IP = wrapIncrement(cpu.IP)
+			newIP := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapAdd(cpu.IP, 2)
+			newCS := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapAdd(cpu.IP, 1)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.CS = newCS
+			cpu.IP = newIP
 			baseClockCycles, decodingCycles, penaltyCycles = 15, 0, 0
-			totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-			logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-			startOfInstruction = IP
-			continue
 		//JMP byte
 		case 0b11101011:
-			IP = wrapIncrement(IP)
-			instruction := readInstruction(startOfInstruction, IP)
-			IP = calculateJumpB(readCodeB(IP), IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = calculateJumpB(cpu.readCodeB(cpu.IP), cpu.IP)
 			baseClockCycles, decodingCycles, penaltyCycles = 15, 0, 0
-			totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-			logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-			startOfInstruction = IP
-			continue
+		//CALL direct intra segment
+		case 0b11101000:
+			cpu.IP = wrapIncrement(cpu.IP)
+			offset := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.push(wrapIncrement(cpu.IP))
+			cpu.IP = calculateJump(offset, cpu.IP)
+			baseClockCycles, decodingCycles, penaltyCycles = 19, 0, getWordTransferPenaltyCycles(cpu.SP)
+		//CALL direct inter segment
+		case 0b10011010:
+			cpu.IP = wrapIncrement(cpu.IP)
+			newIP := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapAdd(cpu.IP, 2)
+			newCS := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapAdd(cpu.IP, 1)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.push(cpu.CS)
+			cpu.push(wrapIncrement(cpu.IP))
+			cpu.CS = newCS
+			cpu.IP = newIP
+			baseClockCycles, decodingCycles, penaltyCycles = 28, 0, 2*getWordTransferPenaltyCycles(cpu.SP)
+		//RET intra segment
+		case 0b11000011:
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			cpu.IP = cpu.pop()
+			baseClockCycles, decodingCycles = 8, 0
+		//RET intra segment with immediate
+		case 0b11000010:
+			cpu.IP = wrapIncrement(cpu.IP)
+			immediate := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SP)
+			cpu.IP = cpu.pop()
+			cpu.SP = wrapAdd(cpu.SP, immediate)
+			baseClockCycles, decodingCycles = 12, 0
+		//RET inter segment
+		case 0b11001011:
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			penaltyCycles = 2 * getWordTransferPenaltyCycles(cpu.SP)
+			cpu.IP = cpu.pop()
+			cpu.CS = cpu.pop()
+			baseClockCycles, decodingCycles = 18, 0
+		//RET inter segment with immediate
+		case 0b11001010:
+			cpu.IP = wrapIncrement(cpu.IP)
+			immediate := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			penaltyCycles = 2 * getWordTransferPenaltyCycles(cpu.SP)
+			cpu.IP = cpu.pop()
+			cpu.CS = cpu.pop()
+			cpu.SP = wrapAdd(cpu.SP, immediate)
+			baseClockCycles, decodingCycles = 17, 0
 		//Conditional jumps
 		case 0b01110100:
 			fallthrough
@@ -396,34 +849,30 @@ func Simulate(logger *log.Logger) error {
 			fallthrough
 		case 0b01111001:
 			var conditions = [...]uint16{
-				0b0100: uint16(ZF),                   //JZ
-				0b1100: uint16(SF ^ OF),              //JL
-				0b1110: uint16(SF ^ OF | ZF&^OF),     //JLE
-				0b0010: uint16(CF),                   //JB
-				0b0110: uint16(CF | ZF),              //JBE
-				0b1010: uint16(PF),                   //JP
-				0b0000: uint16(OF),                   //JO
-				0b1000: uint16(SF),                   //JS
-				0b0101: uint16(ZF ^ 1),               //JNZ
-				0b1101: uint16(SF ^ OF ^ 1 | ZF&^OF), //JGE
-				0b1111: uint16(SF ^ OF ^ 1),          //JG
-				0b0011: uint16(CF ^ 1 | ZF),          //JAE
-				0b0111: uint16(CF ^ 1),               //JA
-				0b1011: uint16(PF ^ 1),               //JNP
-				0b0001: uint16(OF ^ 1),               //JNO
-				0b1001: uint16(SF ^ 1),               //JNS
+				0b0100: uint16(cpu.ZF),                               //JZ
+				0b1100: uint16(cpu.SF ^ cpu.OF),                      //JL
+				0b1110: uint16(cpu.SF ^ cpu.OF | cpu.ZF&^cpu.OF),     //JLE
+				0b0010: uint16(cpu.CF),                               //JB
+				0b0110: uint16(cpu.CF | cpu.ZF),                      //JBE
+				0b1010: uint16(cpu.PF),                               //JP
+				0b0000: uint16(cpu.OF),                               //JO
+				0b1000: uint16(cpu.SF),                               //JS
+				0b0101: uint16(cpu.ZF ^ 1),                           //JNZ
+				0b1101: uint16(cpu.SF ^ cpu.OF ^ 1 | cpu.ZF&^cpu.OF), //JGE
+				0b1111: uint16(cpu.SF ^ cpu.OF ^ 1),                  //JG
+				0b0011: uint16(cpu.CF ^ 1 | cpu.ZF),                  //JAE
+				0b0111: uint16(cpu.CF ^ 1),                           //JA
+				0b1011: uint16(cpu.PF ^ 1),                           //JNP
+				0b0001: uint16(cpu.OF ^ 1),                           //JNO
+				0b1001: uint16(cpu.SF ^ 1),                           //JNS
 			}
 			condition := conditions[currentInstructionByte&0b00001111]
-			IP = wrapIncrement(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
 			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
 			if condition != 0 {
-				instruction := readInstruction(startOfInstruction, IP)
-				IP = calculateJumpB(readCodeB(IP), IP)
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = calculateJumpB(cpu.readCodeB(cpu.IP), cpu.IP)
 				baseClockCycles = 16
-				totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-				logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-				startOfInstruction = IP
-				continue
 			}
 		//LOOP/LOOPZ/LOOPNZ --CX times
 		case 0b11100010:
@@ -431,75 +880,482 @@ func Simulate(logger *log.Logger) error {
 		case 0b11100001:
 			fallthrough
 		case 0b11100000:
-			condition := [3]byte{ZF ^ 1, ZF, 1}[currentInstructionByte&0b00000011]
-			IP = wrapIncrement(IP)
+			condition := [3]byte{cpu.ZF ^ 1, cpu.ZF, 1}[currentInstructionByte&0b00000011]
+			cpu.IP = wrapIncrement(cpu.IP)
 			baseClockCycles, decodingCycles, penaltyCycles = [3]int{5, 6, 5}[currentInstructionByte&0b00000011], 0, 0
-			CX--
-			if CX > 0 && condition != 0 {
-				instruction := readInstruction(startOfInstruction, IP)
-				IP = calculateJumpB(readCodeB(IP), IP)
+			cpu.CX--
+			if cpu.CX > 0 && condition != 0 {
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = calculateJumpB(cpu.readCodeB(cpu.IP), cpu.IP)
 				baseClockCycles = [3]int{19, 18, 17}[currentInstructionByte&0b00000011]
-				totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-				logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-				startOfInstruction = IP
-				continue
 			}
 		//JCXZ
 		case 0b11100011:
-			IP = wrapIncrement(IP)
+			cpu.IP = wrapIncrement(cpu.IP)
 			baseClockCycles, decodingCycles, penaltyCycles = 6, 0, 0
-			if CX == 0 {
-				instruction := readInstruction(startOfInstruction, IP)
-				IP = calculateJumpB(readCodeB(IP), IP)
+			if cpu.CX == 0 {
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = calculateJumpB(cpu.readCodeB(cpu.IP), cpu.IP)
 				baseClockCycles = 18
-				totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-				logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-				startOfInstruction = IP
-				continue
 			}
 
+		//INC/DEC/CALL/JMP/CALL far/JMP far/PUSH R/M
+		case 0b11111110:
+			fallthrough
+		case 0b11111111:
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			if parameter&Shared.RegMask == 0b111000 || (wide == 0 && parameter&0b110000 != 0) {
+				return newInvalidParameterErrorInvalidInstruction(cpu.CS, cpu.IP)
+			}
+			switch parameter & Shared.RegMask {
+			//INC R/M
+			case 0b000000:
+				value, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+				cpu.writeRMValue(parameter, segment, offset, cpu.incAndUpdateFlags(value, wide != 0), wide)
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 3, 0, 15)
+			//DEC R/M
+			case 0b001000:
+				value, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+				cpu.writeRMValue(parameter, segment, offset, cpu.decAndUpdateFlags(value, wide != 0), wide)
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 3, 0, 15)
+			//CALL indirect intra segment
+			case 0b010000:
+				newIP, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.push(wrapIncrement(cpu.IP))
+				cpu.IP = newIP
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 16, 21, 0)
+				penaltyCycles += getWordTransferPenaltyCycles(cpu.SP)
+			//CALL indirect inter segment
+			case 0b011000:
+				if parameter&Shared.ModMask == Shared.RegisterMode {
+					return newInvalidParameterError(cpu.CS, cpu.IP, "far pointer has to be in memory")
+				}
+				segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+				newIP := cpu.readW(segment, offset)
+				newCS := cpu.readW(segment, wrapAdd(offset, 2))
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.push(cpu.CS)
+				cpu.push(wrapIncrement(cpu.IP))
+				cpu.CS = newCS
+				cpu.IP = newIP
+				//both words of the pointer are read
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 0, 37, 0)
+				penaltyCycles = 2*penaltyCycles + 2*getWordTransferPenaltyCycles(cpu.SP)
+			//JMP indirect intra segment
+			case 0b100000:
+				newIP, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = newIP
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 11, 18, 0)
+			//JMP indirect inter segment
+			case 0b101000:
+				if parameter&Shared.ModMask == Shared.RegisterMode {
+					return newInvalidParameterError(cpu.CS, cpu.IP, "far pointer has to be in memory")
+				}
+				segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+				newIP := cpu.readW(segment, offset)
+				newCS := cpu.readW(segment, wrapAdd(offset, 2))
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.CS = newCS
+				cpu.IP = newIP
+				//both words of the pointer are read
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 0, 24, 0)
+				penaltyCycles *= 2
+			//PUSH R/M
+			case 0b110000:
+				value, _, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+				cpu.push(value)
+				cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+				baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 11, 16, 0)
+				penaltyCycles += getWordTransferPenaltyCycles(cpu.SP)
+			}
+
+		//INT
+		case 0b11001101:
+			cpu.IP = wrapIncrement(cpu.IP)
+			interruptType := cpu.readCodeB(cpu.IP)
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+			cpu.interrupt(interruptType)
+			baseClockCycles, decodingCycles = 51, 0
+		//INT3
+		case 0b11001100:
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+			cpu.interrupt(BREAKPOINT_INTERRUPT)
+			baseClockCycles, decodingCycles = 52, 0
+		//INTO
+		case 0b11001110:
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+			if cpu.OF != 0 {
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = wrapIncrement(cpu.IP)
+				penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+				cpu.interrupt(OVERFLOW_INTERRUPT)
+				baseClockCycles = 53
+			}
+		//IRET
+		case 0b11001111:
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+			cpu.interruptReturn()
+			baseClockCycles, decodingCycles = 24, 0
+
+		//MOV memory to accumulator/accumulator to memory
+		case 0b10100000:
+			fallthrough
+		case 0b10100001:
+			fallthrough
+		case 0b10100010:
+			fallthrough
+		case 0b10100011:
+			wide := currentInstructionByte&Shared.WideMask != 0
+			cpu.IP = wrapIncrement(cpu.IP)
+			address := cpu.readCodeW(cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+			if currentInstructionByte&Shared.DirectionMask == 0 {
+				if wide {
+					cpu.AX = cpu.readDataW(address)
+				} else {
+					cpu.AX = writeL(cpu.AX, uint16(cpu.readDataB(address)))
+				}
+			} else {
+				cpu.write(cpu.applySegmentOverride(cpu.DS), address, cpu.AX, wide)
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = 10, 0, 0
+			if wide {
+				penaltyCycles = getWordTransferPenaltyCycles(address)
+			}
+		//XCHG register with accumulator
+		case 0b10010000:
+			fallthrough
+		case 0b10010001:
+			fallthrough
+		case 0b10010010:
+			fallthrough
+		case 0b10010011:
+			fallthrough
+		case 0b10010100:
+			fallthrough
+		case 0b10010101:
+			fallthrough
+		case 0b10010110:
+			fallthrough
+		case 0b10010111:
+			register := Shared.WIDE | currentInstructionByte&0b111
+			value := cpu.readRegister(register)
+			cpu.writeRegister(register, cpu.AX)
+			cpu.AX = value
+			baseClockCycles, decodingCycles, penaltyCycles = 3, 0, 0
+		//XCHG R/M with register
+		case 0b10000110:
+			fallthrough
+		case 0b10000111:
+			wide := Shared.IsolateAndShiftWide(currentInstructionByte)
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			register := wide | parameter&Shared.RegMask>>3
+			value, segment, offset := cpu.readRMValueSegmentAndDisplacementByParameter(parameter, cpu.IP, wide)
+			cpu.writeRMValue(parameter, segment, offset, cpu.readRegister(register), wide)
+			cpu.writeRegister(register, value)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, true, wide != 0, false, 4, 0, 17)
+		//LEA
+		case 0b10001101:
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			if parameter&Shared.ModMask == Shared.RegisterMode {
+				return newInvalidParameterError(cpu.CS, cpu.IP, "effective address has to be in memory")
+			}
+			_, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.writeRegister(Shared.WIDE|parameter&Shared.RegMask>>3, offset)
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			//no memory is accessed, so there is no penalty for odd addresses
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, false, true, 0, 2, 0)
+		//LES/LDS
+		case 0b11000100:
+			fallthrough
+		case 0b11000101:
+			cpu.IP = wrapIncrement(cpu.IP)
+			parameter := cpu.readCodeB(cpu.IP)
+			if parameter&Shared.ModMask == Shared.RegisterMode {
+				return newInvalidParameterError(cpu.CS, cpu.IP, "far pointer has to be in memory")
+			}
+			segment, offset := cpu.calculateSegmentAndDisplacementByParameter(parameter, cpu.IP)
+			cpu.writeRegister(Shared.WIDE|parameter&Shared.RegMask>>3, cpu.read(segment, offset, true))
+			if currentInstructionByte&Shared.WideMask == 0 {
+				cpu.ES = cpu.read(segment, wrapAdd(offset, 2), true)
+			} else {
+				cpu.DS = cpu.read(segment, wrapAdd(offset, 2), true)
+			}
+			cpu.IP = incrementIPByParameter(cpu.IP, parameter)
+			//both words of the pointer are read
+			baseClockCycles, decodingCycles, penaltyCycles = getBaseDecodingAndPenaltyCyclesByParameter(parameter, offset, false, true, true, 0, 16, 0)
+			penaltyCycles *= 2
+		//XLAT
+		case 0b11010111:
+			cpu.AX = writeL(cpu.AX, uint16(cpu.readDataB(wrapAdd(cpu.BX, cpu.AX&_L))))
+			baseClockCycles, decodingCycles, penaltyCycles = 11, 0, 0
+		//CBW
+		case 0b10011000:
+			cpu.AX = signExtend(cpu.AX & _L)
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//CWD
+		case 0b10011001:
+			if cpu.AX&0b1000000000000000 != 0 {
+				cpu.DX = _W_MAX
+			} else {
+				cpu.DX = 0
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = 5, 0, 0
+		//SAHF
+		case 0b10011110:
+			cpu.SetFlags(cpu.Flags()&_H | readH(cpu.AX))
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+		//LAHF
+		case 0b10011111:
+			cpu.AX = writeH(cpu.AX, cpu.Flags()&_L)
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+
+		//IN/OUT fixed port
+		case 0b11100100:
+			fallthrough
+		case 0b11100101:
+			fallthrough
+		case 0b11100110:
+			fallthrough
+		case 0b11100111:
+			cpu.IP = wrapIncrement(cpu.IP)
+			port := uint16(cpu.readCodeB(cpu.IP))
+			penaltyCycles = cpu.transferPort(currentInstructionByte, port, logger)
+			baseClockCycles, decodingCycles = 10, 0
+		//IN/OUT variable port
+		case 0b11101100:
+			fallthrough
+		case 0b11101101:
+			fallthrough
+		case 0b11101110:
+			fallthrough
+		case 0b11101111:
+			penaltyCycles = cpu.transferPort(currentInstructionByte, cpu.DX, logger)
+			baseClockCycles, decodingCycles = 8, 0
+
+		//SALC, which is only executed in the silicon opcode mode
+		case 0b11010110:
+			if cpu.CF != 0 {
+				cpu.AX = writeL(cpu.AX, _L)
+			} else {
+				cpu.AX = writeL(cpu.AX, 0)
+			}
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+
+		//CLC
+		case 0b11111000:
+			cpu.CF = 0
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//STC
+		case 0b11111001:
+			cpu.CF = 1
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//CMC
+		case 0b11110101:
+			cpu.CF ^= 1
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//CLD
+		case 0b11111100:
+			cpu.DF = 0
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//STD
+		case 0b11111101:
+			cpu.DF = 1
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//CLI
+		case 0b11111010:
+			cpu.IF = 0
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+		//STI
+		case 0b11111011:
+			cpu.IF = 1
+			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
+
+		//MOVS/CMPS/STOS/LODS/SCAS
+		case 0b10100100:
+			fallthrough
+		case 0b10100101:
+			fallthrough
+		case 0b10100110:
+			fallthrough
+		case 0b10100111:
+			fallthrough
+		case 0b10101010:
+			fallthrough
+		case 0b10101011:
+			fallthrough
+		case 0b10101100:
+			fallthrough
+		case 0b10101101:
+			fallthrough
+		case 0b10101110:
+			fallthrough
+		case 0b10101111:
+			wide := currentInstructionByte&Shared.WideMask != 0
+			cycleIndex := currentInstructionByte & 0b00001110 >> 1
+			if cpu.repeatPrefix == 0 {
+				penaltyCycles = cpu.executeStringOperation(currentInstructionByte, wide)
+				baseClockCycles, decodingCycles = [8]int{2: 18, 3: 22, 5: 11, 6: 12, 7: 15}[cycleIndex], 0
+			} else if cpu.CX == 0 {
+				baseClockCycles, decodingCycles, penaltyCycles = 9, 0, 0
+			} else {
+				//each iteration is a separate step, so interrupts can be recognized between iterations
+				penaltyCycles = cpu.executeStringOperation(currentInstructionByte, wide)
+				baseClockCycles, decodingCycles = [8]int{2: 17, 3: 22, 5: 10, 6: 13, 7: 15}[cycleIndex], 0
+				if !repeatInProgress {
+					baseClockCycles += 9
+				}
+				cpu.CX--
+				//CMPS and SCAS also end when ZF does not match the prefix. MOVS, STOS and LODS treat both prefixes as REP.
+				compares := currentInstructionByte&0b00000110 == 0b00000110
+				repeatInProgress = cpu.CX != 0 && (!compares || cpu.ZF == cpu.repeatPrefix&Shared.WideMask)
+				if repeatInProgress {
+					instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+					//the 8086 only resumes at the last prefix after an interrupt
+					repeatResumeIP = wrapSub(cpu.IP, 1)
+					cpu.IP = startOfInstruction
+				}
+			}
+
+		//DAA/DAS
+		case 0b00100111:
+			fallthrough
+		case 0b00101111:
+			cpu.decimalAdjustAndUpdateFlags(currentInstructionByte&0b00001000 != 0)
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+		//AAA/AAS
+		case 0b00110111:
+			fallthrough
+		case 0b00111111:
+			cpu.asciiAdjustAndUpdateFlags(currentInstructionByte&0b00001000 != 0)
+			baseClockCycles, decodingCycles, penaltyCycles = 4, 0, 0
+		//AAM
+		case 0b11010100:
+			cpu.IP = wrapIncrement(cpu.IP)
+			baseClockCycles, decodingCycles, penaltyCycles = 83, 0, 0
+			if !cpu.asciiAdjustForMultiplyAndUpdateFlags(cpu.readCodeB(cpu.IP)) {
+				//like divide errors the address of the next instruction is pushed
+				instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+				cpu.IP = wrapIncrement(cpu.IP)
+				penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+				cpu.interrupt(DIVIDE_ERROR_INTERRUPT)
+				baseClockCycles += 51
+			}
+		//AAD
+		case 0b11010101:
+			cpu.IP = wrapIncrement(cpu.IP)
+			cpu.asciiAdjustForDivisionAndUpdateFlags(cpu.readCodeB(cpu.IP))
+			baseClockCycles, decodingCycles, penaltyCycles = 60, 0, 0
+
+		//REP
+		case 0b11110010:
+			fallthrough
+		case 0b11110011:
+			cpu.repeatPrefix = currentInstructionByte
+			cpu.IP = wrapIncrement(cpu.IP)
+			continue
+		//LOCK
+		case 0b11110000:
+			//the bus lock has no effect on a single processor
+			if !repeatInProgress {
+				prefixCycles += 2
+			}
+			cpu.IP = wrapIncrement(cpu.IP)
+			continue
+		//SEGMENT override
+		case 0b00100110:
+			fallthrough
+		case 0b00101110:
+			fallthrough
+		case 0b00110110:
+			fallthrough
+		case 0b00111110:
+			cpu.segmentOverride = currentInstructionByte & Shared.SegMask >> 3
+			cpu.segmentOverridden = true
+			//the prefix is only fetched once for repeated string instructions
+			if !repeatInProgress {
+				prefixCycles += 2
+			}
+			cpu.IP = wrapIncrement(cpu.IP)
+			continue
+
 		//HLT
 		case 0b11110100:
 			baseClockCycles, decodingCycles, penaltyCycles = 2, 0, 0
-			totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-			logStateAndInstruction(readInstruction(startOfInstruction, IP), baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-			return nil
+			if !cpu.canLeaveHalt(trap) {
+				totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
+				cpu.logStateAndInstruction(cpu.readInstruction(startOfInstruction, cpu.IP), baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
+				return nil
+			}
+			halted = true
 
 		default:
-			return newUnsupportedError(CS, IP, "unsupported instruction")
+			return newUnsupportedError(cpu.CS, cpu.IP, "unsupported instruction")
 		}
 
-		instruction := readInstruction(startOfInstruction, IP)
-		IP = wrapIncrement(IP)
+		//instructions that change CS:IP read their instruction bytes before jumping
+		if instruction == nil {
+			instruction = cpu.readInstruction(startOfInstruction, cpu.IP)
+			cpu.IP = wrapIncrement(cpu.IP)
+		}
+		baseClockCycles += prefixCycles
+		prefixCycles = 0
 		totalClockCycles += baseClockCycles + decodingCycles + penaltyCycles
-		logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
-		startOfInstruction = IP
-	}
-}
+		cpu.logStateAndInstruction(instruction, baseClockCycles, decodingCycles, penaltyCycles, totalClockCycles, logger)
+		if err := cpu.checkReadOnlyWrites(codeSegment, startOfInstruction); err != nil {
+			return err
+		}
+		cpu.clockDevices(baseClockCycles + decodingCycles + penaltyCycles)
+		cpu.clearPrefixes()
+		startOfInstruction = cpu.IP
+
+		if halted {
+			idleCycles, pending := cpu.waitForInterrupt(trap)
+			totalClockCycles += idleCycles
+			cpu.logHalt(idleCycles, totalClockCycles, logger)
+			if !pending {
+				return newHaltTimeoutError(cpu.CS, cpu.IP)
+			}
+		}
 
-func Rest() {
-	AX = 0
-	BX = 0
-	CX = 0
-	DX = 0
-	SP = 0
-	BP = 0
-	SI = 0
-	DI = 0
-	IP = 0
-	CS = RESET_CS
-	DS = 0
-	SS = 0
-	ES = 0
-	TF = 0
-	DF = 0
-	IF = 0
-	OF = 0
-	SF = 0
-	ZF = 0
-	AF = 0
-	PF = 0
-	CF = 0
-
-	clear(Memory[:])
+		//interrupts are recognized between instructions and between iterations of repeated string instructions
+		if !inhibitInterrupts {
+			if interruptType, interruptCycles, recognized := cpu.recognizeInterrupt(trap); recognized {
+				if repeatInProgress {
+					//like on the 8086 all prefixes except the last are lost, if a repeated string instruction is interrupted
+					cpu.IP = repeatResumeIP
+					repeatInProgress = false
+				}
+				penaltyCycles = 3 * getWordTransferPenaltyCycles(cpu.SP)
+				//the interrupt sequence pushes the return address to the stack, which could be read-only
+				returnSegment, returnOffset := cpu.CS, cpu.IP
+				cpu.interrupt(interruptType)
+				totalClockCycles += interruptCycles + penaltyCycles
+				cpu.logInterrupt(interruptType, interruptCycles, penaltyCycles, totalClockCycles, logger)
+				if err := cpu.checkReadOnlyWrites(returnSegment, returnOffset); err != nil {
+					return err
+				}
+				cpu.clockDevices(interruptCycles + penaltyCycles)
+				startOfInstruction = cpu.IP
+			}
+		}
+	}
 }
@@ -0,0 +1,60 @@
+package Simulation
+
+// executeStringOperation executes a single iteration of MOVS, CMPS, STOS, LODS or SCAS defined by opcode.
+// The source is DS:SI, unless the segment is overridden, and the destination is ES:DI.
+// SI and DI are incremented, or decremented if DF is set, by the operand size.
+// Returns the penalty cycles for word transfers at odd addresses.
+func (cpu *CPU) executeStringOperation(opcode byte, wide bool) (penaltyCycles int) {
+	var delta uint16 = 1
+	if wide {
+		delta = 2
+	}
+	if cpu.DF != 0 {
+		delta = -delta
+	}
+	sourceSegment := cpu.applySegmentOverride(cpu.DS)
+	switch opcode & 0b11111110 {
+	//MOVS
+	case 0b10100100:
+		cpu.write(cpu.ES, cpu.DI, cpu.read(sourceSegment, cpu.SI, wide), wide)
+		if wide {
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SI) + getWordTransferPenaltyCycles(cpu.DI)
+		}
+		cpu.SI = wrapAdd(cpu.SI, delta)
+		cpu.DI = wrapAdd(cpu.DI, delta)
+	//CMPS
+	case 0b10100110:
+		_ = cpu.subAndUpateFlags(cpu.read(sourceSegment, cpu.SI, wide), cpu.read(cpu.ES, cpu.DI, wide), wide)
+		if wide {
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SI) + getWordTransferPenaltyCycles(cpu.DI)
+		}
+		cpu.SI = wrapAdd(cpu.SI, delta)
+		cpu.DI = wrapAdd(cpu.DI, delta)
+	//STOS
+	case 0b10101010:
+		cpu.write(cpu.ES, cpu.DI, cpu.AX, wide)
+		if wide {
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.DI)
+		}
+		cpu.DI = wrapAdd(cpu.DI, delta)
+	//LODS
+	case 0b10101100:
+		if wide {
+			cpu.AX = cpu.read(sourceSegment, cpu.SI, true)
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.SI)
+		} else {
+			cpu.AX = writeL(cpu.AX, cpu.read(sourceSegment, cpu.SI, false))
+		}
+		cpu.SI = wrapAdd(cpu.SI, delta)
+	//SCAS
+	case 0b10101110:
+		_ = cpu.subAndUpateFlags(cpu.AX, cpu.read(cpu.ES, cpu.DI, wide), wide)
+		if wide {
+			penaltyCycles = getWordTransferPenaltyCycles(cpu.DI)
+		}
+		cpu.DI = wrapAdd(cpu.DI, delta)
+	default:
+		panic("Invalid string instruction")
+	}
+	return
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/P100sch/Intel8086Simulator/Simulation"
 	"github.com/P100sch/Intel8086Simulator/Simulation/Disassembly"
+	"github.com/P100sch/Intel8086Simulator/Simulation/Shared"
 )
 
 func main() {
@@ -16,7 +18,8 @@ func main() {
 	}
 
 	var filePath, outputFilePath string
-	var disassemble, verbose bool
+	var disassemble, verbose, a20Enabled bool
+	var opcodeMode = Shared.STRICT_OPCODES
 
 	if len(os.Args) > 2 {
 		outputFlag := false
@@ -40,6 +43,24 @@ func main() {
 						os.Exit(1)
 					}
 				}
+				if strings.ContainsAny(arg, "u") {
+					if filePath == "" {
+						opcodeMode = Shared.SILICON_OPCODES
+					} else {
+						println("flags need to be passed before the assembly file path")
+						printHelp()
+						os.Exit(1)
+					}
+				}
+				if strings.ContainsAny(arg, "a") {
+					if filePath == "" {
+						a20Enabled = true
+					} else {
+						println("flags need to be passed before the assembly file path")
+						printHelp()
+						os.Exit(1)
+					}
+				}
 				if strings.ContainsAny(arg, "o") {
 					if filePath != "" {
 						outputFlag = true
@@ -73,7 +94,7 @@ func main() {
 
 	if disassemble {
 		var output string
-		output, err = Disassembly.Disassemble(data)
+		output, err = Disassembly.DisassembleWithOpcodeMode(data, opcodeMode)
 		if err != nil {
 			println("Error decoding instructions!")
 			println(err.Error())
@@ -95,20 +116,29 @@ func main() {
 		if verbose {
 			logger = log.Default()
 		}
-		err = Simulation.LoadProgram(data, false)
+		cpu := Simulation.NewCPU()
+		cpu.OpcodeMode = opcodeMode
+		cpu.A20Enabled = a20Enabled
+		if strings.EqualFold(filepath.Ext(filePath), ".com") {
+			err = cpu.LoadCOM(data, Simulation.DEFAULT_PSP_SEGMENT, "")
+		} else if strings.EqualFold(filepath.Ext(filePath), ".exe") {
+			err = cpu.LoadEXE(data, Simulation.DEFAULT_PSP_SEGMENT, "")
+		} else {
+			err = cpu.LoadProgram(data, false)
+		}
 		if err != nil {
 			println("Error loading program!")
 			println(err.Error())
 			os.Exit(5)
 		}
-		err = Simulation.Simulate(logger)
+		err = cpu.Simulate(logger)
 		if err != nil {
 			println("Error running simulation!")
 			println(err.Error())
 			os.Exit(5)
 		}
 		if outputFilePath != "" {
-			err = os.WriteFile(outputFilePath, Simulation.Memory[:], 0644)
+			err = os.WriteFile(outputFilePath, cpu.Memory[:], 0644)
 			if err != nil {
 				println("Error writing file!")
 				println(err.Error())
@@ -119,9 +149,12 @@ func main() {
 }
 
 func printHelp() {
-	println("Intel8086Simulator [-v|d] instructions.bin [-o out.asm/data]")
+	println("Intel8086Simulator [-v|d|u|a] instructions.bin [-o out.asm/data]")
 	println("Simulates the execution of the instruction stream.")
+	println("Files with the extensions .com and .exe are loaded like DOS programs behind a PSP at segment 0x1000.")
 	println("-v Outputs disassembly and the state of the registers after each instruction.")
 	println("-d Only outputs a disassembly of the instruction stream to the console or to the file specified by the `-o` flag.")
+	println("-u Decodes and executes undocumented opcodes like the 8086 instead of reporting them as invalid.")
+	println("-a Enables the A20 gate, so addresses above FFFF:000F reach the high memory area instead of wrapping to 0.")
 	println("-o saves the final state of memory to the specified file.")
 }
@@ -0,0 +1,54 @@
+package tests
+
+import "testing"
+
+func TestA20Gate(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		program    string
+		a20Enabled bool
+		ax         uint16
+	}{
+		//MOV AX, 0xFFFF; MOV DS, AX; MOV AL/AX, [o]; HLT
+		{"wraps to 0", "B8FFFF 8ED8 A01002 F4", false, 0xFF5A},
+		{"reaches the high memory area", "B8FFFF 8ED8 A01002 F4", true, 0xFFA5},
+		//the MOV AX at address 0 is the high byte
+		{"word wraps to 0", "B8FFFF 8ED8 A10F00 F4", false, 0xB834},
+		{"word reaches the high memory area", "B8FFFF 8ED8 A10F00 F4", true, 0x1234},
+		//the offset wraps to the JMP at the reset vector
+		{"word wraps within the segment", "B8FFFF 8ED8 A1FFFF F4", true, 0xEA78},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			cpu.A20Enabled = test.a20Enabled
+			poke(t, cpu, 0x200, "5A")
+			poke(t, cpu, 0x100200, "A5")
+			poke(t, cpu, 0xFFFFF, "34")
+			poke(t, cpu, 0x100000, "12")
+			poke(t, cpu, 0x10FFEF, "78")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+		})
+	}
+}
+
+func TestA20GateWrites(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		a20Enabled bool
+		address    int
+	}{
+		{"wraps to 0", false, 0x200},
+		{"reaches the high memory area", true, 0x100200},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			//MOV AX, 0xFFFF; MOV DS, AX; MOV AX, 0x1234; MOV [0x0210], AX; HLT
+			cpu := newTestCPU(t, "B8FFFF 8ED8 B83412 A31002 F4")
+			cpu.A20Enabled = test.a20Enabled
+			simulate(t, cpu)
+			if written := uint16(cpu.Memory[test.address]) | uint16(cpu.Memory[test.address+1])<<8; written != 0x1234 {
+				t.Errorf("0x%06x contains 0x%04x, expected 0x1234", test.address, written)
+			}
+		})
+	}
+}
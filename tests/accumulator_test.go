@@ -0,0 +1,42 @@
+package tests
+
+import "testing"
+
+func TestAccumulatorMove(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+		//address receives word
+		address uint16
+		word    uint16
+		cycles  string
+	}{
+		//MOV AX, 0xFFFF; MOV AL/AX, [a]; HLT
+		{"byte from memory", "B8FFFF A00002 F4", 0xFF11, 0x200, 0x2211, "10"},
+		{"byte from odd address", "B8FFFF A00102 F4", 0xFF22, 0x200, 0x2211, "10"},
+		{"word from memory", "B8FFFF A10002 F4", 0x2211, 0x200, 0x2211, "10"},
+		{"word from odd address", "B8FFFF A10102 F4", 0x3322, 0x200, 0x2211, "14"},
+		//MOV AX, 0xAABB; MOV [a], AL/AX; HLT
+		{"byte to memory", "B8BBAA A20402 F4", 0xAABB, 0x204, 0x66BB, "10"},
+		{"word to memory", "B8BBAA A30402 F4", 0xAABB, 0x204, 0xAABB, "10"},
+		{"word to odd address", "B8BBAA A30502 F4", 0xAABB, 0x205, 0xAABB, "14"},
+		//MOV AX, 0x0020; MOV ES, AX; MOV AX, ES:[0]; HLT
+		{"segment override from memory", "B82000 8EC0 26A10000 F4", 0x2211, 0x200, 0x2211, "12"},
+		//MOV AX, 0x0020; MOV ES, AX; MOV ES:[8], AX; HLT
+		{"segment override to memory", "B82000 8EC0 26A30800 F4", 0x0020, 0x208, 0x0020, "12"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x200, "11223344556677")
+			trace := simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "memory", readWord(cpu, 0, test.address), test.word)
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
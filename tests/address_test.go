@@ -0,0 +1,23 @@
+package tests
+
+import "testing"
+
+func TestByteDisplacement(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+	}{
+		//MOV BX/BP, 0x0210; MOV AX, [BX/BP+d8]; HLT
+		{"positive", "BB0E02 8B4702 F4", 0x4433},
+		{"negative", "BB1002 8B47FE F4", 0x2211},
+		{"negative based on BP", "BD1002 8B46FE F4", 0x2211},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x20E, "11223344")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import "testing"
+
+func TestCarryArithmeticFlags(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		program            string
+		ax                 uint16
+		cf, of, zf, sf, af byte
+	}{
+		//MOV AX, CF or 0; PUSH AX; POPF; MOV AX, a; operation; HLT
+		{"ADC carry in", "B80100 50 9D B8FFFF 150000 F4", 0x0000, 1, 0, 1, 0, 1},
+		{"ADC overflow by carry", "B80100 50 9D B8FF7F 150000 F4", 0x8000, 0, 1, 0, 1, 1},
+		{"ADC byte overflow", "B80000 50 9D B87F00 1401 F4", 0x0080, 0, 1, 0, 1, 1},
+		{"SBB borrow in", "B80100 50 9D B80000 1D0000 F4", 0xFFFF, 1, 0, 0, 1, 1},
+		{"SBB overflow by borrow", "B80100 50 9D B80080 1D0000 F4", 0x7FFF, 0, 1, 0, 0, 1},
+		{"SBB byte overflow", "B80000 50 9D B88000 1C01 F4", 0x007F, 0, 1, 0, 0, 1},
+		//INC and DEC keep CF
+		{"INC keeps CF set", "B80100 50 9D B8FFFF 40 F4", 0x0000, 1, 0, 1, 0, 1},
+		{"INC keeps CF cleared", "B80000 50 9D B80F00 40 F4", 0x0010, 0, 0, 0, 0, 1},
+		{"INC overflow", "B80100 50 9D B8FF7F 40 F4", 0x8000, 1, 1, 0, 1, 1},
+		{"INC byte overflow", "B80000 50 9D B87F00 FEC0 F4", 0x0080, 0, 1, 0, 1, 1},
+		{"DEC keeps CF cleared", "B80000 50 9D B80000 48 F4", 0xFFFF, 0, 0, 0, 1, 1},
+		{"DEC overflow", "B80100 50 9D B80080 48 F4", 0x7FFF, 1, 1, 0, 0, 1},
+		{"DEC byte overflow", "B80000 50 9D B88000 FEC8 F4", 0x007F, 0, 1, 0, 0, 1},
+		//NEG sets CF unless the operand is 0
+		{"NEG 0", "B80100 50 9D B80000 F7D8 F4", 0x0000, 0, 0, 1, 0, 0},
+		{"NEG 1", "B80000 50 9D B80100 F7D8 F4", 0xFFFF, 1, 0, 0, 1, 1},
+		{"NEG overflow", "B80000 50 9D B80080 F7D8 F4", 0x8000, 1, 1, 0, 1, 0},
+		{"NEG byte overflow", "B80000 50 9D B88000 F6D8 F4", 0x0080, 1, 1, 0, 1, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectFlags(t, "CF", cpu.CF, test.cf)
+			expectFlags(t, "OF", cpu.OF, test.of)
+			expectFlags(t, "ZF", cpu.ZF, test.zf)
+			expectFlags(t, "SF", cpu.SF, test.sf)
+			expectFlags(t, "AF", cpu.AF, test.af)
+		})
+	}
+}
+
+func TestCarryArithmeticCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV BX, 0x0200; operation; HLT
+		{"ADC register", "BB0002 11D8 F4", "3"},
+		{"ADC memory with register", "BB0002 1107 F4", "21"},
+		{"SBB accumulator immediate", "BB0002 1C01 F4", "4"},
+		{"SBB register with memory", "BB0002 1B07 F4", "14"},
+		{"INC word register", "BB0002 40 F4", "2"},
+		{"INC byte register", "BB0002 FEC0 F4", "3"},
+		{"DEC memory", "BB0002 FF0F F4", "20"},
+		{"NEG register", "BB0002 F7D8 F4", "3"},
+		{"NEG memory", "BB0002 F71F F4", "21"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
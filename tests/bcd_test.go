@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation/Disassembly"
+)
+
+func TestAdjustFlags(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		program            string
+		ax                 uint16
+		cf, af, of, sf, zf byte
+	}{
+		//MOV AX, flags; PUSH AX; POPF; MOV AX, a; DAA/DAS/AAA/AAS/AAD; HLT
+		{"DAA", "B80000 50 9D B89A00 27 F4", 0x0000, 1, 1, 0, 0, 1},
+		{"DAA carry", "B80100 50 9D B81200 27 F4", 0x0072, 1, 0, 0, 0, 0},
+		{"DAA threshold with AF", "B81000 50 9D B89A00 27 F4", 0x00A0, 0, 1, 0, 1, 0},
+		{"DAA above threshold with AF", "B81000 50 9D B8A000 27 F4", 0x0006, 1, 1, 0, 0, 0},
+		{"DAA overflow", "B80000 50 9D B87A00 27 F4", 0x0080, 0, 1, 1, 1, 0},
+		{"DAS threshold with AF", "B81000 50 9D B89A00 2F F4", 0x0094, 0, 1, 0, 1, 0},
+		{"DAS above threshold with AF", "B81000 50 9D B8A000 2F F4", 0x003A, 1, 1, 1, 0, 0},
+		{"DAS overflow", "B81000 50 9D B88000 2F F4", 0x007A, 0, 1, 1, 0, 0},
+		{"AAA", "B80000 50 9D B83500 37 F4", 0x0005, 0, 0, 0, 0, 0},
+		{"AAA full AL", "B80000 50 9D B8FA00 37 F4", 0x0100, 1, 1, 0, 0, 1},
+		{"AAA overflow", "B80000 50 9D B87A00 37 F4", 0x0100, 1, 1, 1, 1, 0},
+		{"AAS full AL", "B81000 50 9D B80500 3F F4", 0xFF0F, 1, 1, 0, 1, 0},
+		{"AAS overflow", "B81000 50 9D B88000 3F F4", 0xFF0A, 1, 1, 1, 0, 0},
+		{"AAD carry", "B80000 50 9D B8FF09 D510 F4", 0x008F, 1, 0, 0, 1, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectFlags(t, "CF", cpu.CF, test.cf)
+			expectFlags(t, "AF", cpu.AF, test.af)
+			expectFlags(t, "OF", cpu.OF, test.of)
+			expectFlags(t, "SF", cpu.SF, test.sf)
+			expectFlags(t, "ZF", cpu.ZF, test.zf)
+		})
+	}
+}
+
+func TestAdjustBase(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+	}{
+		//MOV AX, a; AAM/AAD base; HLT
+		{"AAM", "B84100 D40A F4", 0x0605},
+		{"AAM 16", "B85B00 D410 F4", 0x050B},
+		{"AAM 7", "B86400 D407 F4", 0x0E02},
+		{"AAD", "B80506 D50A F4", 0x0041},
+		{"AAD 16", "B80B05 D510 F4", 0x005B},
+		{"AAD 7", "B8020E D507 F4", 0x0064},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			program := decodeHex(t, test.program)
+			asm, err := Disassembly.Disassemble(program[3:5])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(asm, test.name+" ;") {
+				t.Errorf("disassembled to %q, expected %q", asm, test.name)
+			}
+
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+		})
+	}
+}
+
+func TestAAMDivideError(t *testing.T) {
+	asm, err := Disassembly.Disassemble(decodeHex(t, "D400"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(asm, "AAM 0 ;") {
+		t.Errorf("disassembled to %q, expected %q", asm, "AAM 0")
+	}
+
+	//MOV AX, 0x0001; AAM 0; HLT
+	cpu := newInterruptTestCPU(t, "B80100 D400 F4")
+	simulate(t, cpu)
+	expectInterrupt(t, cpu, 0, 0x105)
+	expectRegister(t, "AX", cpu.AX, 0x0001)
+}
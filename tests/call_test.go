@@ -0,0 +1,78 @@
+package tests
+
+import "testing"
+
+func TestCallAndReturn(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax, sp  uint16
+		//the word below the stack is the return IP for near and the return CS for far calls
+		pushed uint16
+		call   string
+		cycles string
+	}{
+		//CALL +4; MOV AL, 1; HLT; NOP; RET
+		{"near direct", "E80400 B001 F4 90 C3", 1, 0, 0x0003, "CALL", "19"},
+		//MOV BX, 0x0006; CALL BX; HLT; MOV AL, 1; RET
+		{"near register", "BB0600 FFD3 F4 B001 C3", 1, 0, 0x0005, "CALL BX", "16"},
+		//MOV word [0x200], 0x000B; CALL [0x200]; HLT; MOV AL, 1; RET
+		{"near memory", "C70600020B00 FF160002 F4 B001 C3", 1, 0, 0x000A, "CALL  [512]", "27"},
+		//CALL 0010:0000; HLT
+		{"far direct", "9A00001000 F4", 0x0010, 0, 0x0000, "CALL 16:0", "28"},
+		//MOV word [0x200], 0; MOV word [0x202], 0x0010; CALL far [0x200]; HLT
+		{"far memory", "C70600020000 C70602021000 FF1E0002 F4", 0x0010, 0, 0x0000, "CALL far", "43"},
+		//CALL +1; HLT; RET 4
+		{"near with immediate", "E80100 F4 C20400", 0, 4, 0x0003, "CALL", "19"},
+		//CALL 0000:0006; HLT; RETF 6
+		{"far with immediate", "9A06000000 F4 CA0600", 0, 6, 0x0000, "CALL 0:6", "28"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			//MOV AX, CS; RETF at 0010:0000
+			poke(t, cpu, 0x100, "8CC8 CB")
+			trace := simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "SP", cpu.SP, test.sp)
+			expectRegister(t, "CS", cpu.CS, 0)
+			expectRegister(t, "pushed word", readWord(cpu, 0, 0xFFFE), test.pushed)
+			if line := findTraceLine(t, trace, test.call); clocks(t, line) != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, clocks(t, line), test.cycles)
+			}
+		})
+	}
+}
+
+func TestFarReturnAddress(t *testing.T) {
+	//CALL 0010:0000; HLT
+	cpu := newTestCPU(t, "9A00001000 F4")
+	poke(t, cpu, 0x100, "CB")
+	simulate(t, cpu)
+	expectRegister(t, "pushed CS", readWord(cpu, 0, 0xFFFE), 0)
+	expectRegister(t, "pushed IP", readWord(cpu, 0, 0xFFFC), 0x0005)
+	expectRegister(t, "IP", cpu.IP, 0x0005)
+}
+
+func TestReturnCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ret     string
+		cycles  string
+	}{
+		//CALL; HLT; return
+		{"RET", "E80100 F4 C3", "RET ;", "8"},
+		{"RET immediate", "E80100 F4 C20400", "RET 4", "12"},
+		{"RETF", "9A06000000 F4 CB", "RETF ;", "18"},
+		{"RETF immediate", "9A06000000 F4 CA0600", "RETF 6", "17"},
+		//MOV SP, 1; CALL; HLT; RET with the return address at an odd address
+		{"RET odd", "BC0100 E80100 F4 C3", "RET ;", "12"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			if line := findTraceLine(t, trace, test.ret); clocks(t, line) != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, clocks(t, line), test.cycles)
+			}
+		})
+	}
+}
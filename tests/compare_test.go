@@ -0,0 +1,27 @@
+package tests
+
+import "testing"
+
+func TestCompareWithMemoryOperand(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		program    string
+		zf, cf, sf byte
+	}{
+		//MOV word [0x200], 5; MOV AX, a; CMP [0x200], AX or CMP AX, [0x200]; HLT
+		{"memory to register equal", "C70600020500 B80500 39060002 F4", 1, 0, 0},
+		{"memory to register below", "C70600020500 B80600 39060002 F4", 0, 1, 1},
+		{"register to memory below", "C70600020500 B80400 3B060002 F4", 0, 1, 1},
+		{"byte with displacement", "C70600020500 BB0001 B80600 38870001 F4", 0, 1, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			//the displacement is not executed as an instruction
+			expectRegister(t, "word [0x200]", readWord(cpu, 0, 0x200), 5)
+			expectFlags(t, "ZF", cpu.ZF, test.zf)
+			expectFlags(t, "CF", cpu.CF, test.cf)
+			expectFlags(t, "SF", cpu.SF, test.sf)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIndependentCPUs(t *testing.T) {
+	programs := []struct {
+		program string
+		ax      uint16
+		count   uint16
+	}{
+		//MOV AX, a; MOV [0x200], AX; MOV CX, n; ADD WORD [0x202], 1; LOOP -7; HLT
+		{"B81111 89060002 B90001 8306020201 E2F9 F4", 0x1111, 0x0100},
+		{"B82222 89060002 B98000 8306020201 E2F9 F4", 0x2222, 0x0080},
+	}
+	for i := range 8 {
+		test := programs[i%len(programs)]
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "CX", cpu.CX, 0)
+			expectRegister(t, "[0x200]", readWord(cpu, 0, 0x200), test.ax)
+			expectRegister(t, "[0x202]", readWord(cpu, 0, 0x202), test.count)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package tests
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func expectLoadError(t *testing.T, err error) {
+	t.Helper()
+	var loadError Simulation.LoadError
+	if !errors.As(err, &loadError) {
+		t.Errorf("expected a load error, got %v", err)
+	}
+}
+
+func TestLoadCOM(t *testing.T) {
+	const segment uint16 = 0x2000
+	cpu := Simulation.NewCPU()
+	//the stack word is cleared by the loader
+	cpu.Memory[int(segment)<<4+0xFFFE] = 0x55
+	if err := cpu.LoadCOM(decodeHex(t, "B44C CD21"), segment, " /a b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	psp := cpu.Memory[int(segment)<<4:]
+	if psp[0] != 0xCD || psp[1] != 0x20 {
+		t.Errorf("PSP starts with % x, expected INT 20h", psp[:2])
+	}
+	if memoryTop := readWord(cpu, segment, 0x02); memoryTop != Simulation.DOS_MEMORY_TOP_SEGMENT {
+		t.Errorf("memory top is 0x%04x, expected 0x%04x", memoryTop, Simulation.DOS_MEMORY_TOP_SEGMENT)
+	}
+	if psp[0x80] != 9 || string(psp[0x81:0x8A]) != " /a b.txt" || psp[0x8A] != '\r' {
+		t.Errorf("command tail is %q", psp[0x80:0x8B])
+	}
+	if image := psp[0x100:0x104]; string(image) != string(decodeHex(t, "B44CCD21")) {
+		t.Errorf("image at offset 0x100 is % x", image)
+	}
+	if stack := readWord(cpu, segment, 0xFFFE); stack != 0 {
+		t.Errorf("word at SS:FFFE is 0x%04x, expected 0", stack)
+	}
+	if cpu.CS != segment || cpu.DS != segment || cpu.ES != segment || cpu.SS != segment {
+		t.Errorf("segments are CS:%04x DS:%04x ES:%04x SS:%04x, expected %04x", cpu.CS, cpu.DS, cpu.ES, cpu.SS, segment)
+	}
+	if cpu.IP != 0x100 || cpu.SP != 0xFFFE {
+		t.Errorf("IP:%04x SP:%04x, expected IP:0100 SP:fffe", cpu.IP, cpu.SP)
+	}
+	if err := cpu.Simulate(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadCOMRejects(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	expectLoadError(t, cpu.LoadCOM(make([]byte, 0xFEFF), 0x1000, ""))
+	expectLoadError(t, cpu.LoadCOM(nil, 0x1000, " "+strings.Repeat("x", Simulation.MAX_COMMAND_TAIL_LENGTH)))
+	expectLoadError(t, cpu.LoadCOM(nil, 0x0020, ""))
+	expectLoadError(t, cpu.LoadCOM(nil, 0xF001, ""))
+	if err := cpu.LoadCOM(make([]byte, 0xFEFE), 0x1000, strings.Repeat("x", Simulation.MAX_COMMAND_TAIL_LENGTH)); err != nil {
+		t.Errorf("largest image and command tail rejected: %v", err)
+	}
+}
+
+func TestDOSTermination(t *testing.T) {
+	for name, program := range map[string]string{
+		"RET":       "C3",
+		"INT 20h":   "CD20",
+		"AH=00h":    "B400 CD21",
+		"AH=4Ch":    "B8004C CD21",
+		"AH=09h":    "B409 CD21",
+		"PSP INT20": "E9FDFE",
+	} {
+		cpu := Simulation.NewCPU()
+		if err := cpu.LoadCOM(decodeHex(t, program+"40"), Simulation.DEFAULT_PSP_SEGMENT, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := cpu.Simulate(nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if cpu.AX&0xFF != 0 {
+			t.Errorf("%s: execution continued after termination", name)
+		}
+	}
+
+}
+
+func TestLoadCOMClearsRegisters(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	cpu.AX, cpu.BX, cpu.CX, cpu.DX = 1, 2, 3, 4
+	cpu.SI, cpu.DI, cpu.BP = 5, 6, 7
+	if err := cpu.LoadCOM(decodeHex(t, "C3"), Simulation.DEFAULT_PSP_SEGMENT, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, register := range []struct {
+		name  string
+		value uint16
+	}{{"AX", cpu.AX}, {"BX", cpu.BX}, {"CX", cpu.CX}, {"DX", cpu.DX}, {"SI", cpu.SI}, {"DI", cpu.DI}, {"BP", cpu.BP}} {
+		expectRegister(t, register.name, register.value, 0)
+	}
+	expectFlags(t, "IF", cpu.IF, 1)
+}
+
+type exeHeader struct {
+	minimumAllocation, maximumAllocation uint16
+	ss, sp, ip, cs                       uint16
+	relocations                          [][2]uint16
+}
+
+// buildEXE creates an MZ executable with image and the relocation table of header directly behind the header
+func buildEXE(image []byte, header exeHeader) []byte {
+	headerSize := (0x1C + 4*len(header.relocations) + 0xF) &^ 0xF
+	fileSize := headerSize + len(image)
+	data := make([]byte, fileSize)
+	words := []uint16{
+		0x5A4D,
+		uint16(fileSize % 512),
+		uint16((fileSize + 511) / 512),
+		uint16(len(header.relocations)),
+		uint16(headerSize >> 4),
+		header.minimumAllocation,
+		header.maximumAllocation,
+		header.ss,
+		header.sp,
+		0,
+		header.ip,
+		header.cs,
+		0x1C,
+	}
+	for i, word := range words {
+		binary.LittleEndian.PutUint16(data[i*2:], word)
+	}
+	for i, relocation := range header.relocations {
+		binary.LittleEndian.PutUint16(data[0x1C+i*4:], relocation[0])
+		binary.LittleEndian.PutUint16(data[0x1E+i*4:], relocation[1])
+	}
+	copy(data[headerSize:], image)
+	return data
+}
+
+// exeImage has code in its first paragraph: MOV AX, SEG data; MOV DS, AX; MOV AL, [2]; MOV AH, 4Ch; INT 21h.
+// The second paragraph contains data with a far pointer to 0001:0000 at offset 4.
+var exeImage = "B80100 8ED8 A00200 B44C CD21 00000000" + "0000 58 00 0000 0100 0000000000000000"
+
+func TestLoadEXE(t *testing.T) {
+	const segment uint16 = 0x2000
+	const loadSegment = segment + 0x10
+	cpu := Simulation.NewCPU()
+	data := buildEXE(decodeHex(t, exeImage), exeHeader{
+		minimumAllocation: 0x10,
+		maximumAllocation: 0x20,
+		ss:                0x02,
+		sp:                0x0100,
+		cs:                0,
+		ip:                0,
+		relocations:       [][2]uint16{{0x0001, 0x0000}, {0x0006, 0x0001}},
+	})
+	if err := cpu.LoadEXE(data, segment, " x"); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.CS != loadSegment || cpu.IP != 0 || cpu.SS != loadSegment+2 || cpu.SP != 0x0100 {
+		t.Errorf("CS:IP is %04x:%04x and SS:SP %04x:%04x", cpu.CS, cpu.IP, cpu.SS, cpu.SP)
+	}
+	if cpu.DS != segment || cpu.ES != segment {
+		t.Errorf("DS:%04x ES:%04x do not point to the PSP", cpu.DS, cpu.ES)
+	}
+	if fixup := readWord(cpu, loadSegment, 0x0001); fixup != loadSegment+1 {
+		t.Errorf("relocated MOV immediate is 0x%04x, expected 0x%04x", fixup, loadSegment+1)
+	}
+	if fixup := readWord(cpu, loadSegment+1, 0x0006); fixup != loadSegment+1 {
+		t.Errorf("relocated far pointer segment is 0x%04x, expected 0x%04x", fixup, loadSegment+1)
+	}
+	//PSP, 2 image paragraphs and the maximum allocation
+	if memoryTop := readWord(cpu, segment, 0x02); memoryTop != segment+0x10+2+0x20 {
+		t.Errorf("memory top is 0x%04x", memoryTop)
+	}
+	if cpu.Memory[int(segment)<<4+0x80] != 2 {
+		t.Error("command tail is missing from the PSP")
+	}
+	if err := cpu.Simulate(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.AX != 0x4C58 {
+		t.Errorf("AX is 0x%04x, expected the data byte 0x58 in AL", cpu.AX)
+	}
+}
+
+func TestLoadEXEAllocation(t *testing.T) {
+	image := decodeHex(t, exeImage)
+	relocations := [][2]uint16{{0x0001, 0x0000}}
+
+	//the maximum allocation is limited to conventional memory
+	cpu := Simulation.NewCPU()
+	if err := cpu.LoadEXE(buildEXE(image, exeHeader{maximumAllocation: 0xFFFF, relocations: relocations}), 0x1000, ""); err != nil {
+		t.Fatal(err)
+	}
+	if memoryTop := readWord(cpu, 0x1000, 0x02); memoryTop != Simulation.DOS_MEMORY_TOP_SEGMENT {
+		t.Errorf("memory top is 0x%04x, expected 0x%04x", memoryTop, Simulation.DOS_MEMORY_TOP_SEGMENT)
+	}
+
+	//the minimum allocation has to fit
+	cpu = Simulation.NewCPU()
+	fitting := uint16(Simulation.DOS_MEMORY_TOP_SEGMENT - 0x1000 - 0x10 - 2)
+	if err := cpu.LoadEXE(buildEXE(image, exeHeader{minimumAllocation: fitting, maximumAllocation: 0xFFFF}), 0x1000, ""); err != nil {
+		t.Errorf("fitting minimum allocation rejected: %v", err)
+	}
+	expectLoadError(t, cpu.LoadEXE(buildEXE(image, exeHeader{minimumAllocation: fitting + 1, maximumAllocation: 0xFFFF}), 0x1000, ""))
+	expectLoadError(t, cpu.LoadEXE(buildEXE(image, exeHeader{}), Simulation.DOS_MEMORY_TOP_SEGMENT, ""))
+
+	//minimum and maximum allocation 0 load the image high
+	cpu = Simulation.NewCPU()
+	if err := cpu.LoadEXE(buildEXE(image, exeHeader{ip: 0, relocations: relocations}), 0x1000, ""); err != nil {
+		t.Fatal(err)
+	}
+	loadSegment := Simulation.DOS_MEMORY_TOP_SEGMENT - 2
+	if cpu.CS != loadSegment {
+		t.Errorf("CS is 0x%04x, expected the image at 0x%04x", cpu.CS, loadSegment)
+	}
+	if fixup := readWord(cpu, loadSegment, 0x0001); fixup != loadSegment+1 {
+		t.Errorf("relocated MOV immediate is 0x%04x, expected 0x%04x", fixup, loadSegment+1)
+	}
+	if memoryTop := readWord(cpu, 0x1000, 0x02); memoryTop != Simulation.DOS_MEMORY_TOP_SEGMENT {
+		t.Errorf("memory top is 0x%04x, expected 0x%04x", memoryTop, Simulation.DOS_MEMORY_TOP_SEGMENT)
+	}
+}
+
+func TestLoadEXERejects(t *testing.T) {
+	image := decodeHex(t, exeImage)
+	valid := buildEXE(image, exeHeader{relocations: [][2]uint16{{0x0001, 0x0000}}})
+	modified := func(offset int, value uint16) []byte {
+		data := append([]byte(nil), valid...)
+		binary.LittleEndian.PutUint16(data[offset:], value)
+		return data
+	}
+	cpu := Simulation.NewCPU()
+	if err := cpu.LoadEXE(modified(0, 0x4D5A), 0x1000, ""); err != nil {
+		t.Errorf("ZM signature rejected: %v", err)
+	}
+	for name, data := range map[string][]byte{
+		"signature":                modified(0, 0x5A4E),
+		"truncated header":         valid[:0x1B],
+		"pages past EOF":           modified(0x04, 3),
+		"last page past EOF":       modified(0x02, uint16(len(valid)%512+1)),
+		"header smaller than 0x1C": modified(0x08, 1),
+		"header past image":        modified(0x08, 0x10),
+		"relocation table":         modified(0x18, uint16(len(valid)-2)),
+		"relocation count":         modified(0x06, 0x100),
+		"relocation target":        modified(0x1C, uint16(len(image)-1)),
+	} {
+		err := cpu.LoadEXE(data, 0x1000, "")
+		if err == nil {
+			t.Errorf("%s: accepted", name)
+			continue
+		}
+		expectLoadError(t, err)
+	}
+}
+
+func TestLoadEXEThroughMemoryBus(t *testing.T) {
+	const segment uint16 = 0x2000
+	const loadAddress = int(segment+0x10) << 4
+	cpu := Simulation.NewCPU()
+	device := newMemoryDevice()
+	cpu.MapMemory(loadAddress, loadAddress+0x1F, device)
+	data := buildEXE(decodeHex(t, exeImage), exeHeader{
+		maximumAllocation: 0x10,
+		relocations:       [][2]uint16{{0x0001, 0x0000}},
+	})
+	if err := cpu.LoadEXE(data, segment, ""); err != nil {
+		t.Fatal(err)
+	}
+	if device.data[loadAddress] != 0xB8 || cpu.Memory[loadAddress] != 0 {
+		t.Error("the image was not loaded through the memory bus")
+	}
+	if fixup := uint16(device.data[loadAddress+1]) | uint16(device.data[loadAddress+2])<<8; fixup != segment+0x11 {
+		t.Errorf("relocated MOV immediate is 0x%04x, expected 0x%04x", fixup, segment+0x11)
+	}
+}
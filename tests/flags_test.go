@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func TestFlagControl(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		flags   uint16
+	}{
+		//MOV AX, flags; PUSH AX; POPF; flag instruction; HLT
+		{"CLC", "B8D50E 50 9D F8 F4", 0xFED6},
+		{"STC", "B80000 50 9D F9 F4", 0xF003},
+		{"CMC set", "B80000 50 9D F5 F4", 0xF003},
+		{"CMC clear", "B80100 50 9D F5 F4", 0xF002},
+		{"CLD", "B8D50E 50 9D FC F4", 0xFAD7},
+		{"STD", "B80000 50 9D FD F4", 0xF402},
+		{"CLI", "B8D50E 50 9D FA F4", 0xFCD7},
+		{"STI", "B80000 50 9D FB F4", 0xF202},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			trace := simulate(t, cpu)
+			expectRegister(t, "FLAGS", cpu.Flags(), test.flags)
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != "2" {
+				t.Errorf("%s took %s cycles, expected 2", line, actual)
+			}
+		})
+	}
+}
+
+func TestFlagsWord(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	cpu.SetFlags(0)
+	//the reserved bits 1 and 12 to 15 read as 1
+	expectRegister(t, "cleared FLAGS", cpu.Flags(), 0xF002)
+	cpu.SetFlags(0xFFFF)
+	expectRegister(t, "set FLAGS", cpu.Flags(), 0xFFD7)
+
+	for _, flag := range []struct {
+		name string
+		bit  uint16
+		flag *byte
+	}{
+		{"CF", 0, &cpu.CF},
+		{"PF", 2, &cpu.PF},
+		{"AF", 4, &cpu.AF},
+		{"ZF", 6, &cpu.ZF},
+		{"SF", 7, &cpu.SF},
+		{"TF", 8, &cpu.TF},
+		{"IF", 9, &cpu.IF},
+		{"DF", 10, &cpu.DF},
+		{"OF", 11, &cpu.OF},
+	} {
+		cpu.SetFlags(1 << flag.bit)
+		expectFlags(t, flag.name, *flag.flag, 1)
+		expectRegister(t, flag.name+" FLAGS", cpu.Flags(), 0xF002|1<<flag.bit)
+		*flag.flag = 0
+		expectRegister(t, "cleared "+flag.name+" FLAGS", cpu.Flags(), 0xF002)
+	}
+}
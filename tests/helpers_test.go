@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+// decodeHex decodes the hex bytes in data. Spaces in data are ignored.
+func decodeHex(t *testing.T, data string) []byte {
+	t.Helper()
+	bytes, err := hex.DecodeString(strings.ReplaceAll(data, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes
+}
+
+// newTestCPU creates a CPU with program loaded at address 0
+func newTestCPU(t *testing.T, program string) *Simulation.CPU {
+	t.Helper()
+	cpu := Simulation.NewCPU()
+	if err := cpu.LoadProgram(decodeHex(t, program), true); err != nil {
+		t.Fatal(err)
+	}
+	return cpu
+}
+
+// simulate runs cpu until HLT and returns the trace
+func simulate(t *testing.T, cpu *Simulation.CPU) []string {
+	t.Helper()
+	builder := strings.Builder{}
+	err := cpu.Simulate(log.New(&builder, "", 0))
+	trace := strings.Split(strings.TrimSuffix(builder.String(), "\n"), "\n")
+	if err != nil {
+		t.Fatal(err, "\n", strings.Join(trace, "\n"))
+	}
+	return trace
+}
+
+// readWord reads the word at segment:offset from Memory
+func readWord(cpu *Simulation.CPU, segment, offset uint16) uint16 {
+	return binary.LittleEndian.Uint16(cpu.Memory[int(segment)<<4+int(offset):])
+}
+
+func expectRegister(t *testing.T, name string, actual, expected uint16) {
+	t.Helper()
+	if actual != expected {
+		t.Errorf("%s is 0x%04x, expected 0x%04x", name, actual, expected)
+	}
+}
+
+func expectFlags(t *testing.T, name string, actual, expected byte) {
+	t.Helper()
+	if actual != expected {
+		t.Errorf("%s is %d, expected %d", name, actual, expected)
+	}
+}
+
+// findTraceLine gets the first line of trace that contains text
+func findTraceLine(t *testing.T, trace []string, text string) string {
+	t.Helper()
+	for _, line := range trace {
+		if strings.Contains(line, text) {
+			return line
+		}
+	}
+	t.Fatalf("%q not found in trace:\n%s", text, strings.Join(trace, "\n"))
+	return ""
+}
+
+// clocks gets the cycles of a trace line
+func clocks(t *testing.T, line string) string {
+	t.Helper()
+	position := strings.Index(line, "bytes +")
+	if position == -1 {
+		t.Fatalf("no clocks in %q", line)
+	}
+	return strings.SplitN(line[position+len("bytes +"):], " ", 2)[0]
+}
+
+// poke writes the hex bytes in data to memory at address
+func poke(t *testing.T, cpu *Simulation.CPU, address int, data string) {
+	t.Helper()
+	copy(cpu.Memory[address:], decodeHex(t, data))
+}
+
+// newInterruptTestCPU creates a CPU that runs program at 0000:0100 with the stack below 0000:1000.
+// The handlers of the interrupt types 0 to 4 are HLT instructions at 0000:0200 to 0000:0204.
+func newInterruptTestCPU(t *testing.T, program string) *Simulation.CPU {
+	t.Helper()
+	cpu := Simulation.NewCPU()
+	for interruptType := range 5 {
+		poke(t, cpu, interruptType*4, "00020000")
+		cpu.Memory[interruptType*4] = byte(interruptType)
+		cpu.Memory[0x200+interruptType] = 0xF4
+	}
+	poke(t, cpu, 0x100, program)
+	cpu.CS = 0
+	cpu.IP = 0x100
+	cpu.SP = 0x1000
+	return cpu
+}
+
+// expectInterrupt checks that the handler of interruptType was entered with returnIP pushed
+func expectInterrupt(t *testing.T, cpu *Simulation.CPU, interruptType byte, returnIP uint16) {
+	t.Helper()
+	if cpu.CS != 0 || cpu.IP != 0x200+uint16(interruptType) {
+		t.Fatalf("stopped at %04x:%04x instead of the handler of interrupt %d", cpu.CS, cpu.IP, interruptType)
+	}
+	if pushedIP := readWord(cpu, cpu.SS, cpu.SP); pushedIP != returnIP {
+		t.Errorf("pushed IP is 0x%04x, expected 0x%04x", pushedIP, returnIP)
+	}
+}
+
+// expectNoInterrupt checks that the program ended at its final HLT at expectedIP
+func expectNoInterrupt(t *testing.T, cpu *Simulation.CPU, expectedIP uint16) {
+	t.Helper()
+	if cpu.IP != expectedIP {
+		t.Fatalf("stopped at %04x:%04x instead of 0000:%04x", cpu.CS, cpu.IP, expectedIP)
+	}
+}
+
+// memoryDevice is a RAM on the memory bus, which is not backed by Memory and counts the accesses it receives
+type memoryDevice struct {
+	data          map[int]byte
+	reads, writes int
+}
+
+func newMemoryDevice() *memoryDevice {
+	return &memoryDevice{data: map[int]byte{}}
+}
+
+func (m *memoryDevice) ReadMemory(address int) byte {
+	m.reads++
+	return m.data[address]
+}
+
+func (m *memoryDevice) WriteMemory(address int, value byte) {
+	m.data[address] = value
+	m.writes++
+}
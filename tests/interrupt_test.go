@@ -0,0 +1,69 @@
+package tests
+
+import "testing"
+
+func TestSoftwareInterrupt(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		program       string
+		interruptType byte
+		returnIP      uint16
+		instruction   string
+		cycles        string
+	}{
+		//INT 3; HLT
+		{"INT3", "CC F4", 3, 0x101, "INT3", "52"},
+		//INT 2; HLT
+		{"INT", "CD02 F4", 2, 0x102, "INT 2", "51"},
+		//MOV AL, 0x7F; ADD AL, 1; INTO; HLT
+		{"INTO overflow", "B07F 0401 CE F4", 4, 0x105, "INTO", "53"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newInterruptTestCPU(t, test.program)
+			trace := simulate(t, cpu)
+			expectInterrupt(t, cpu, test.interruptType, test.returnIP)
+			if line := findTraceLine(t, trace, test.instruction); clocks(t, line) != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, clocks(t, line), test.cycles)
+			}
+		})
+	}
+}
+
+func TestINTOWithoutOverflow(t *testing.T) {
+	//MOV AL, 1; ADD AL, 1; INTO; HLT
+	cpu := newInterruptTestCPU(t, "B001 0401 CE F4")
+	trace := simulate(t, cpu)
+	expectNoInterrupt(t, cpu, 0x105)
+	if line := findTraceLine(t, trace, "INTO"); clocks(t, line) != "4" {
+		t.Errorf("%s took %s cycles, expected 4", line, clocks(t, line))
+	}
+}
+
+func TestInterruptPushesState(t *testing.T) {
+	//MOV AX, IF|CF; PUSH AX; POPF; INT 3; HLT
+	cpu := newInterruptTestCPU(t, "B80102 50 9D CC F4")
+	//the same program at 0010:0000
+	cpu.CS = 0x0010
+	cpu.IP = 0
+	simulate(t, cpu)
+	expectInterrupt(t, cpu, 3, 0x0006)
+	expectRegister(t, "pushed CS", readWord(cpu, cpu.SS, cpu.SP+2), 0x0010)
+	expectRegister(t, "pushed FLAGS", readWord(cpu, cpu.SS, cpu.SP+4), 0xF203)
+	expectRegister(t, "SP", cpu.SP, 0x1000-6)
+	expectFlags(t, "IF", cpu.IF, 0)
+	expectFlags(t, "CF", cpu.CF, 1)
+}
+
+func TestIRET(t *testing.T) {
+	//MOV AX, OF|CF; PUSH AX; POPF; INT 3; PUSHF; POP BX; HLT
+	cpu := newInterruptTestCPU(t, "B80108 50 9D CC 9C 5B F4")
+	//CMP AX, AX; IRET as the handler of INT 3
+	poke(t, cpu, 0x203, "39C0 CF")
+	trace := simulate(t, cpu)
+	expectNoInterrupt(t, cpu, 0x108)
+	expectRegister(t, "FLAGS", cpu.BX, 0xF803)
+	expectRegister(t, "SP", cpu.SP, 0x1000)
+	if line := findTraceLine(t, trace, "IRET"); clocks(t, line) != "24" {
+		t.Errorf("%s took %s cycles, expected 24", line, clocks(t, line))
+	}
+}
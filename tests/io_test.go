@@ -0,0 +1,42 @@
+package tests
+
+import "testing"
+
+type portRecorder struct {
+	writes []uint16
+}
+
+func (p *portRecorder) In(_ uint16, _ bool) uint16 {
+	return 0x42
+}
+
+func (p *portRecorder) Out(port, _ uint16, _ bool) {
+	p.writes = append(p.writes, port)
+}
+
+func TestUnmappedPorts(t *testing.T) {
+	//IN AL, 60h; MOV BL, AL; IN AX, 62h; OUT 64h, AL; OUT 66h, AX; IN AL, 70h; HLT
+	cpu := newTestCPU(t, "E460 88C3 E562 E664 E766 E470 F4")
+	recorder := &portRecorder{}
+	cpu.MapPorts(0x70, 0x70, recorder)
+	//no logger must not panic
+	if err := cpu.Simulate(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.BX&0xFF != 0xFF {
+		t.Errorf("unmapped byte read 0x%02x, expected 0xff", cpu.BX&0xFF)
+	}
+	if cpu.AX != 0xFF42 {
+		t.Errorf("AX is 0x%04x, expected 0xff42 after unmapped word read and mapped byte read", cpu.AX)
+	}
+	if len(recorder.writes) != 0 {
+		t.Errorf("unmapped writes reached a handler at ports %v", recorder.writes)
+	}
+
+	cpu = newTestCPU(t, "E562 F4")
+	trace := simulate(t, cpu)
+	if cpu.AX != 0xFFFF {
+		t.Errorf("unmapped word read 0x%04x, expected 0xffff", cpu.AX)
+	}
+	findTraceLine(t, trace, "warning: IN from unmapped port 0x0063")
+}
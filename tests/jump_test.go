@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func TestJumpNear(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+		trace   string
+	}{
+		//JMP +2; MOV AL, 2; MOV AL, 1; HLT
+		{"forward", "E90200 B002 B001 F4", 1, "JMP 5 ;"},
+		//JMP SHORT +4; HLT; MOV AL, 1; HLT; JMP -6
+		{"backward", "EB04 F4 B001 F4 E9FAFF", 1, "JMP 65533 ;"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			trace := simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			if line := findTraceLine(t, trace, test.trace); clocks(t, line) != "15" {
+				t.Errorf("%s took %s cycles, expected 15", line, clocks(t, line))
+			}
+		})
+	}
+}
+
+func TestJumpIndirect(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax, cs  uint16
+		jump    string
+		cycles  string
+	}{
+		//MOV BX, 6; JMP BX; HLT; INC AX; HLT
+		{"near register", "BB0600 FFE3 F4 40 F4", 1, 0, "JMP BX", "11"},
+		//MOV word [0x200], 0x000B; JMP [0x200]; HLT; INC AX; HLT
+		{"near memory", "C70600020B00 FF260002 F4 40 F4", 1, 0, "JMP  [512]", "24"},
+		//MOV word [0x200], 0; MOV word [0x202], 0x0010; JMP far [0x200]; HLT
+		{"far memory", "C70600020000 C70602021000 FF2E0002 F4", 0x0010, 0x0010, "JMP far", "30"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			//MOV AX, CS; HLT at 0010:0000
+			poke(t, cpu, 0x100, "8CC8 F4")
+			trace := simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "CS", cpu.CS, test.cs)
+			if line := findTraceLine(t, trace, test.jump); clocks(t, line) != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, clocks(t, line), test.cycles)
+			}
+		})
+	}
+}
+
+func TestFarPointerInRegister(t *testing.T) {
+	//JMP far BX and CALL far BX; HLT
+	for _, program := range []string{"FFEB F4", "FFDB F4"} {
+		err := newTestCPU(t, program).Simulate(nil)
+		var decodingError *Simulation.DecodingError
+		if !errors.As(err, &decodingError) {
+			t.Errorf("%s returned %v, expected a decoding error", program, err)
+		}
+	}
+}
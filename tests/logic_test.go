@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func TestLogicalFlags(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		program        string
+		ax             uint16
+		cf, of, sf, zf byte
+		pf             byte
+		//AF is undefined after AND, OR, XOR and TEST, NOT changes no flag
+		undefinedAF bool
+	}{
+		//MOV AX, OF|SF|ZF|AF|PF|CF; PUSH AX; POPF; operation; HLT
+		{"AND", "B8D508 50 9D B8F0F0 BB008F 21D8 F4", 0x8000, 0, 0, 1, 0, 1, true},
+		{"OR immediate", "B8D508 50 9D B80000 0C01 F4", 0x0001, 0, 0, 0, 0, 0, true},
+		{"XOR", "B8D508 50 9D 31C0 F4", 0x0000, 0, 0, 0, 1, 1, true},
+		{"TEST accumulator", "B8D508 50 9D B80180 A90080 F4", 0x8001, 0, 0, 1, 0, 1, true},
+		//the immediate follows the displacement
+		{"TEST word memory", "B8D508 50 9D C70600020180 F70600020080 F4", 0x08D5, 0, 0, 1, 0, 1, true},
+		{"TEST byte memory", "B8D508 50 9D BB0002 C6471003 F64710FE F4", 0x08D5, 0, 0, 0, 0, 0, true},
+		{"NOT", "B8D508 50 9D B80F00 F7D0 F4", 0xFFF0, 1, 1, 1, 1, 1, false},
+	} {
+		for _, mode := range []struct {
+			name      string
+			behaviour Simulation.UndefinedFlagBehaviour
+			af        byte
+		}{
+			{"cleared", Simulation.UNDEFINED_FLAGS_CLEARED, 0},
+			{"set", Simulation.UNDEFINED_FLAGS_SET, 1},
+			{"preserved", Simulation.UNDEFINED_FLAGS_PRESERVED, 1},
+		} {
+			t.Run(test.name+" "+mode.name, func(t *testing.T) {
+				cpu := newTestCPU(t, test.program)
+				cpu.UndefinedFlags = mode.behaviour
+				simulate(t, cpu)
+				expectRegister(t, "AX", cpu.AX, test.ax)
+				expectFlags(t, "CF", cpu.CF, test.cf)
+				expectFlags(t, "OF", cpu.OF, test.of)
+				expectFlags(t, "SF", cpu.SF, test.sf)
+				expectFlags(t, "ZF", cpu.ZF, test.zf)
+				expectFlags(t, "PF", cpu.PF, test.pf)
+				if test.undefinedAF {
+					expectFlags(t, "AF", cpu.AF, mode.af)
+				} else {
+					expectFlags(t, "AF", cpu.AF, 1)
+				}
+			})
+		}
+	}
+}
+
+func TestTestDoesNotStore(t *testing.T) {
+	//MOV word [0x200], 0x8001; TEST word [0x200], 0x8000; MOV AX, 0x0F0F; TEST AX, 0xF0F0; HLT
+	cpu := newTestCPU(t, "C70600020180 F70600020080 B80F0F A9F0F0 F4")
+	simulate(t, cpu)
+	expectRegister(t, "[0x200]", readWord(cpu, 0, 0x200), 0x8001)
+	expectRegister(t, "AX", cpu.AX, 0x0F0F)
+	expectFlags(t, "ZF", cpu.ZF, 1)
+}
+
+func TestLogicalCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV BX, 0x0200; operation; HLT
+		{"AND register", "BB0002 21D8 F4", "3"},
+		{"AND register with memory", "BB0002 2307 F4", "14"},
+		{"AND memory with register", "BB0002 2107 F4", "21"},
+		{"AND accumulator immediate", "BB0002 2401 F4", "4"},
+		{"OR register immediate", "BB0002 83C901 F4", "4"},
+		{"OR memory immediate", "BB0002 800F01 F4", "22"},
+		{"XOR memory odd", "BB0102 3107 F4", "29"},
+		{"TEST register", "BB0002 85D8 F4", "3"},
+		{"TEST register with memory", "BB0002 8507 F4", "14"},
+		{"TEST accumulator immediate", "BB0002 A80F F4", "4"},
+		{"TEST register immediate", "BB0002 F7C30100 F4", "5"},
+		{"TEST memory immediate", "BB0002 F6070F F4", "16"},
+		{"NOT register", "BB0002 F7D0 F4", "3"},
+		{"NOT memory", "BB0002 F617 F4", "21"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
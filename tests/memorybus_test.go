@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func TestMemoryHandler(t *testing.T) {
+	//MOV AX, 0x1234; MOV [0x200], AX; MOV BX, [0x200]; HLT
+	cpu := newTestCPU(t, "B83412 A30002 8B1E0002 F4")
+	device := newMemoryDevice()
+	cpu.MapMemory(0x200, 0x2FF, device)
+	simulate(t, cpu)
+	if device.data[0x200] != 0x34 || device.data[0x201] != 0x12 || readWord(cpu, 0, 0x200) != 0 {
+		t.Error("the word was not written to the handler")
+	}
+	expectRegister(t, "BX", cpu.BX, 0x1234)
+
+	//the mapping added last handles overlapping addresses
+	cpu = newTestCPU(t, "B83412 A30002 F4")
+	cpu.MapMemory(0x200, 0x2FF, device)
+	overlapping := newMemoryDevice()
+	cpu.MapMemory(0x201, 0x201, overlapping)
+	simulate(t, cpu)
+	if overlapping.writes != 1 || overlapping.data[0x201] != 0x12 {
+		t.Error("the later mapping did not receive the high byte")
+	}
+}
+
+func TestLoadProgramThroughMemoryBus(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	device := newMemoryDevice()
+	cpu.MapMemory(0, 0xF, device)
+	if err := cpu.LoadProgram(decodeHex(t, "B80100 F4"), false); err != nil {
+		t.Fatal(err)
+	}
+	if device.data[0] != 0xB8 || device.data[3] != 0xF4 || cpu.Memory[0] != 0 {
+		t.Error("the program was not loaded through the memory bus")
+	}
+}
+
+func TestInstructionFetchReadsOnce(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	device := newMemoryDevice()
+	cpu.MapMemory(0, 0xF, device)
+	//ES: MOV AX, [0x0000]; HLT
+	for i, value := range decodeHex(t, "26 A10000 F4") {
+		device.data[i] = value
+	}
+	cpu.CS = 0
+	simulate(t, cpu)
+	//5 code bytes and the 2 bytes of the operand
+	if device.reads != 7 {
+		t.Errorf("the handler was read %d times, expected 7", device.reads)
+	}
+}
+
+func TestReadOnlyInstructionsDoNotWrite(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		writes  int
+	}{
+		//MOV CL, n; instruction with the operand [0x200]; HLT
+		{"CMP byte immediate", "B100 803E000200 F4", 0},
+		{"CMP word immediate", "B100 813E00023412 F4", 0},
+		{"CMP word sign extended immediate", "B100 833E0002FF F4", 0},
+		{"CMP register", "B100 39060002 F4", 0},
+		{"TEST register", "B100 85060002 F4", 0},
+		{"shift by CL 0", "B100 D3260002 F4", 0},
+		{"shift by CL 1", "B101 D3260002 F4", 2},
+		{"ADD byte immediate", "B100 80060002 01 F4", 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			device := newMemoryDevice()
+			cpu.MapMemory(0x200, 0x201, device)
+			simulate(t, cpu)
+			if device.writes != test.writes {
+				t.Errorf("the handler received %d writes, expected %d", device.writes, test.writes)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import "testing"
+
+func TestMultiplyFlags(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax, dx  uint16
+		carry   byte
+	}{
+		//MOV AX, a; MOV BX, b; MUL/IMUL BL/BX; HLT
+		{"MUL byte fits", "B80200 BB0300 F6E3 F4", 0x0006, 0, 0},
+		{"MUL byte", "B81000 BB1000 F6E3 F4", 0x0100, 0, 1},
+		{"MUL word fits", "B8FFFF BB0100 F7E3 F4", 0xFFFF, 0, 0},
+		{"MUL word", "B8FFFF BB0200 F7E3 F4", 0xFFFE, 1, 1},
+		{"IMUL byte negative fits", "B8FE00 BB0300 F6EB F4", 0xFFFA, 0, 0},
+		{"IMUL byte", "B84000 BB0200 F6EB F4", 0x0080, 0, 1},
+		{"IMUL word negative fits", "B8FFFF BB0040 F7EB F4", 0xC000, 0xFFFF, 0},
+		{"IMUL word", "B80040 BB0200 F7EB F4", 0x8000, 0, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "DX", cpu.DX, test.dx)
+			expectFlags(t, "CF", cpu.CF, test.carry)
+			expectFlags(t, "OF", cpu.OF, test.carry)
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax, dx  uint16
+	}{
+		//MOV DX, d; MOV AX, a; MOV BX, b; DIV/IDIV BL/BX; HLT
+		{"DIV byte", "BA0000 B86400 BB0700 F6F3 F4", 0x020E, 0},
+		{"DIV word", "BA0100 B80000 BB0300 F7F3 F4", 0x5555, 0x0001},
+		{"IDIV byte", "BA0000 B89CFF BB0700 F6FB F4", 0xFEF2, 0},
+		{"IDIV byte -127", "BA0000 B881FF BB0100 F6FB F4", 0x0081, 0},
+		{"IDIV word", "BAFFFF B89CFF BB0700 F7FB F4", 0xFFF2, 0xFFFE},
+		{"IDIV word -32767", "BAFFFF B80180 BB0100 F7FB F4", 0x8001, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newInterruptTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectNoInterrupt(t, cpu, 0x10B)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "DX", cpu.DX, test.dx)
+		})
+	}
+}
+
+func TestDivideError(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax, dx  uint16
+	}{
+		//MOV DX, d; MOV AX, a; MOV BX, b; DIV/IDIV BL/BX; HLT
+		{"DIV byte by zero", "BA0000 B83412 BB0000 F6F3 F4", 0x1234, 0},
+		{"DIV word by zero", "BA0000 B83412 BB0000 F7F3 F4", 0x1234, 0},
+		{"DIV byte overflow", "BA0000 B80010 BB1000 F6F3 F4", 0x1000, 0},
+		{"DIV word overflow", "BA0100 B80000 BB0100 F7F3 F4", 0, 1},
+		{"IDIV byte overflow", "BA0000 B80001 BB0100 F6FB F4", 0x0100, 0},
+		{"IDIV byte -128", "BA0000 B880FF BB0100 F6FB F4", 0xFF80, 0},
+		{"IDIV word -32768", "BAFFFF B80080 BB0100 F7FB F4", 0x8000, 0xFFFF},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newInterruptTestCPU(t, test.program)
+			simulate(t, cpu)
+			//the address of the instruction after DIV/IDIV is pushed
+			expectInterrupt(t, cpu, 0, 0x10B)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "DX", cpu.DX, test.dx)
+		})
+	}
+}
+
+func TestMultiplyDivideCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV AX, a; MOV BX, b; MUL/IMUL/DIV/IDIV BL/BX; HLT
+		{"MUL byte minimum", "B80200 BB0000 F6E3 F4", "70"},
+		{"MUL byte maximum", "B80200 BBFF00 F6E3 F4", "77"},
+		{"MUL word maximum", "B80200 BBFFFF F7E3 F4", "133"},
+		{"IMUL byte magnitude", "B80200 BBFF00 F6EB F4", "82"},
+		{"DIV byte small quotient", "B80100 BB0200 F6F3 F4", "80"},
+		{"DIV byte large quotient", "B8FF00 BB0100 F6F3 F4", "90"},
+		{"IDIV word", "BA0000 B80F00 BB0100 F7FB F4", "169"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
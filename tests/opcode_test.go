@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation/Disassembly"
+	"github.com/P100sch/Intel8086Simulator/Simulation/Shared"
+)
+
+func TestSiliconOpcodes(t *testing.T) {
+	for _, test := range []struct {
+		name, program, instruction, assembly string
+		ax, sp, cs                           uint16
+	}{
+		//STC/CLC; SALC; HLT
+		{"SALC carry", "F9 D6 F4", "D6", "SALC", 0x00FF, 0, 0},
+		{"SALC no carry", "F8 D6 F4", "D6", "SALC", 0x0000, 0, 0},
+		//MOV AX, 0x1000; PUSH AX; POP CS; HLT at 1000:0005
+		{"POP CS", "B80010 50 0F", "0F", "POP CS", 0x1000, 0, 0x1000},
+		//XOR AX, AX; [INC AX]; JZ alias +1; INC AX; HLT
+		{"JZ alias taken", "31C0 6401 40 F4", "6401", "JE  $+3", 0x0000, 0, 0},
+		{"JZ alias not taken", "31C0 40 6401 40 F4", "6401", "JE  $+3", 0x0002, 0, 0},
+		//LOCK alias INC AX; HLT
+		{"LOCK alias", "F140 F4", "F140", "LOCK INC AX", 0x0001, 0, 0},
+		//CALL 0x0005; INC AX; HLT; RET alias
+		{"RET alias", "E80200 40 F4 C1", "C1", "RET", 0x0001, 0, 0},
+		{"RET imm alias", "E80200 40 F4 C00200", "C00200", "RET 2", 0x0001, 2, 0},
+		//CALL 0000:0007; INC AX; HLT; RETF alias
+		{"RETF alias", "9A07000000 40 F4 C9", "C9", "RETF", 0x0001, 0, 0},
+		{"RETF imm alias", "9A07000000 40 F4 C80400", "C80400", "RETF 4", 0x0001, 4, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Disassembly.Disassemble(decodeHex(t, test.instruction)); err == nil {
+				t.Error("strict disassembly accepted the undocumented opcode")
+			}
+			asm, err := Disassembly.DisassembleWithOpcodeMode(decodeHex(t, test.instruction), Shared.SILICON_OPCODES)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(asm, test.assembly+" ;") {
+				t.Errorf("disassembled to %q, expected %q", asm, test.assembly)
+			}
+
+			cpu := newTestCPU(t, test.program)
+			if err := cpu.Simulate(nil); err == nil {
+				t.Error("STRICT_OPCODES executed the undocumented opcode")
+			}
+
+			cpu = newTestCPU(t, test.program)
+			cpu.OpcodeMode = Shared.SILICON_OPCODES
+			poke(t, cpu, 0x10005, "F4")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "SP", cpu.SP, test.sp)
+			expectRegister(t, "CS", cpu.CS, test.cs)
+		})
+	}
+}
+
+func TestPrefixOrder(t *testing.T) {
+	for prefixes, assembly := range map[string]string{
+		"26F3A4":   "ES REPZ MOVSB",
+		"F326A4":   "REPZ ES MOVSB",
+		"26F08B07": "ES LOCK MOV AX, [BX]",
+		"F0268B07": "LOCK MOV AX, ES:[BX]",
+	} {
+		asm, err := Disassembly.Disassemble(decodeHex(t, prefixes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(asm, assembly+" ;") {
+			t.Errorf("%s disassembled to %q, expected %q", prefixes, asm, assembly)
+		}
+	}
+}
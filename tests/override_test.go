@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestSegmentOverride(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+		zf      byte
+		//address receives word, if it is not 0
+		address int
+		word    uint16
+	}{
+		{"r/m", "BB1000 268B07", 0x1234, 0, 0, 0},
+		{"r/m based on BP", "BD1000 268B4600", 0x1234, 0, 0, 0},
+		{"r/m based on BP without override", "BD1000 8B4600", 0x5678, 0, 0, 0},
+		{"LODSW", "BE1000 26AD", 0x1234, 0, 0, 0},
+		{"MOVSW", "BE1000 BF2000 26A5", 0x0040, 0, 0x220, 0x1234},
+		//the stack and the destination of string instructions always use SS and ES
+		{"PUSH", "B8CDAB 2650", 0xABCD, 0, 0x4FE, 0xABCD},
+		{"STOSW", "B8CDAB BF3000 2EAB", 0xABCD, 0, 0x230, 0xABCD},
+		{"SCASW", "B83412 BF1000 3EAF", 0x1234, 1, 0, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			//MOV AX, 0x0020; MOV ES, AX; MOV AX, 0x0040; MOV SS, AX; MOV SP, 0x0100; instructions; HLT
+			cpu := newTestCPU(t, "B82000 8EC0 B84000 8ED0 BC0001"+test.program+"F4")
+			poke(t, cpu, 0x210, "3412")
+			poke(t, cpu, 0x410, "7856")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectFlags(t, "ZF", cpu.ZF, test.zf)
+			if test.address != 0 {
+				expectRegister(t, "written word", binary.LittleEndian.Uint16(cpu.Memory[test.address:]), test.word)
+			}
+			//nothing is written relative to the overriding segment
+			for _, address := range []int{0x2FE, 0x30} {
+				if written := binary.LittleEndian.Uint16(cpu.Memory[address:]); written != 0 {
+					t.Errorf("0x%05x was written with 0x%04x", address, written)
+				}
+			}
+		})
+	}
+}
+
+func TestSegmentOverrideCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		{"MOV r/m", "BB1000 268B07", "15"},
+		{"ADD r/m", "BB1000 260307", "16"},
+		{"LODSB", "BE1000 26AC", "14"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program+"F4"))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
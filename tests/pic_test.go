@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+// newInitializedPIC creates a single, edge triggered PIC with interrupt types 0x08-0x0F
+func newInitializedPIC() *Simulation.PIC {
+	pic := Simulation.NewPIC()
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x13, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x08, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x01, false)
+	return pic
+}
+
+func expectAcknowledge(t *testing.T, pic *Simulation.PIC, expected byte) {
+	t.Helper()
+	if !pic.InterruptRequested() {
+		t.Fatalf("no interrupt requested, expected type 0x%02x", expected)
+	}
+	if interruptType := pic.AcknowledgeInterrupt(); interruptType != expected {
+		t.Fatalf("acknowledged type 0x%02x, expected 0x%02x", interruptType, expected)
+	}
+}
+
+func expectNoRequest(t *testing.T, pic *Simulation.PIC) {
+	t.Helper()
+	if pic.InterruptRequested() {
+		t.Fatal("unexpected interrupt request")
+	}
+}
+
+func readISR(pic *Simulation.PIC) byte {
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x0B, false)
+	isr := byte(pic.In(Simulation.PIC_COMMAND_PORT, false))
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x0A, false)
+	return isr
+}
+
+func TestPICInitialization(t *testing.T) {
+	pic := Simulation.NewPIC()
+	pic.SetIRQ(0, true)
+	expectNoRequest(t, pic)
+
+	//cascaded mode with ICW4 expects ICW2, ICW3 and ICW4 before OCW1
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x11, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x70, false)
+	expectNoRequest(t, pic)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x04, false)
+	expectNoRequest(t, pic)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x01, false)
+	if imr := pic.In(Simulation.PIC_DATA_PORT, false); imr != 0 {
+		t.Errorf("ICW3 or ICW4 were written to IMR 0x%02x", imr)
+	}
+	//ICW1 cleared the request of the earlier edge
+	expectNoRequest(t, pic)
+	pic.SetIRQ(0, false)
+	pic.SetIRQ(0, true)
+	expectAcknowledge(t, pic, 0x70)
+
+	//single mode without ICW4 is ready after ICW2, the low bits of ICW2 are ignored
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x12, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x0D, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0xAA, false)
+	if imr := pic.In(Simulation.PIC_DATA_PORT, false); imr != 0xAA {
+		t.Errorf("IMR is 0x%02x, expected 0xaa after OCW1", imr)
+	}
+	pic.SetIRQ(2, true)
+	expectAcknowledge(t, pic, 0x0A)
+}
+
+func TestPICMasking(t *testing.T) {
+	pic := newInitializedPIC()
+	pic.Out(Simulation.PIC_DATA_PORT, 0x01, false)
+	pic.SetIRQ(0, true)
+	expectNoRequest(t, pic)
+	if irr := pic.In(Simulation.PIC_COMMAND_PORT, false); irr != 0x01 {
+		t.Errorf("IRR is 0x%02x, expected 0x01 for a masked request", irr)
+	}
+	pic.Out(Simulation.PIC_DATA_PORT, 0x00, false)
+	expectAcknowledge(t, pic, 0x08)
+}
+
+func TestPICFixedPriority(t *testing.T) {
+	pic := newInitializedPIC()
+	pic.SetIRQ(5, true)
+	pic.SetIRQ(3, true)
+	expectAcknowledge(t, pic, 0x0B)
+	//IRQ 5 has a lower priority than IRQ 3 in service
+	expectNoRequest(t, pic)
+	//IRQ 1 nests
+	pic.SetIRQ(1, true)
+	expectAcknowledge(t, pic, 0x09)
+	if isr := readISR(pic); isr != 0b00001010 {
+		t.Errorf("ISR is 0b%08b, expected IRQ 1 and 3 in service", isr)
+	}
+	//non-specific EOI ends IRQ 1, the highest priority in service
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x20, false)
+	if isr := readISR(pic); isr != 0b00001000 {
+		t.Errorf("ISR is 0b%08b after non-specific EOI, expected IRQ 3 in service", isr)
+	}
+	expectNoRequest(t, pic)
+	//specific EOI for IRQ 3
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x63, false)
+	if isr := readISR(pic); isr != 0 {
+		t.Errorf("ISR is 0b%08b after specific EOI", isr)
+	}
+	expectAcknowledge(t, pic, 0x0D)
+}
+
+func TestPICRotatingPriority(t *testing.T) {
+	pic := newInitializedPIC()
+	pic.SetIRQ(3, true)
+	expectAcknowledge(t, pic, 0x0B)
+	//rotate on non-specific EOI makes IRQ 3 the lowest priority
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0xA0, false)
+	pic.SetIRQ(2, true)
+	pic.SetIRQ(4, true)
+	expectAcknowledge(t, pic, 0x0C)
+	//rotate on specific EOI for IRQ 4 makes IRQ 5 the highest priority
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0xE4, false)
+	pic.SetIRQ(6, true)
+	expectAcknowledge(t, pic, 0x0E)
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x20, false)
+	//set priority makes IRQ 1 the lowest priority
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0xC1, false)
+	pic.SetIRQ(1, true)
+	expectAcknowledge(t, pic, 0x0A)
+}
+
+func TestPICPoll(t *testing.T) {
+	pic := newInitializedPIC()
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x0C, false)
+	if poll := pic.In(Simulation.PIC_COMMAND_PORT, false); poll != 0 {
+		t.Errorf("poll without request is 0x%02x", poll)
+	}
+	pic.SetIRQ(6, true)
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x0C, false)
+	if poll := pic.In(Simulation.PIC_COMMAND_PORT, false); poll != 0x86 {
+		t.Errorf("poll is 0x%02x, expected 0x86", poll)
+	}
+	//polling acknowledges the request
+	if isr := readISR(pic); isr != 0b01000000 {
+		t.Errorf("ISR is 0b%08b after poll, expected IRQ 6 in service", isr)
+	}
+	expectNoRequest(t, pic)
+}
+
+func TestPICSpuriousInterrupt(t *testing.T) {
+	pic := newInitializedPIC()
+	pic.SetIRQ(4, true)
+	if !pic.InterruptRequested() {
+		t.Fatal("no interrupt requested")
+	}
+	pic.SetIRQ(4, false)
+	if interruptType := pic.AcknowledgeInterrupt(); interruptType != 0x0F {
+		t.Errorf("acknowledged type 0x%02x, expected spurious IRQ 7", interruptType)
+	}
+	if isr := readISR(pic); isr != 0 {
+		t.Errorf("spurious IRQ 7 set ISR 0b%08b", isr)
+	}
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+// programPIT selects mode and LSB then MSB access for channel and writes count
+func programPIT(pit *Simulation.PIT, channel, mode byte, bcd bool, count uint16) {
+	controlWord := channel<<6 | 0b11<<4 | mode<<1
+	if bcd {
+		controlWord |= 1
+	}
+	pit.Out(Simulation.PIT_CONTROL_PORT, uint16(controlWord), false)
+	pit.Out(Simulation.PIT_CHANNEL_0_PORT+uint16(channel), count&0xFF, false)
+	pit.Out(Simulation.PIT_CHANNEL_0_PORT+uint16(channel), count>>8, false)
+}
+
+// readPIT latches and reads the count of channel
+func readPIT(pit *Simulation.PIT, channel byte) uint16 {
+	pit.Out(Simulation.PIT_CONTROL_PORT, uint16(channel<<6), false)
+	port := Simulation.PIT_CHANNEL_0_PORT + uint16(channel)
+	return pit.In(port, false) | pit.In(port, false)<<8
+}
+
+// tick advances pit by PIT clocks
+func tick(pit *Simulation.PIT, clocks int) {
+	pit.Clock(clocks * Simulation.CPU_CYCLES_PER_PIT_CLOCK)
+}
+
+// recordOutput returns the levels of the output of channel after each of clocks PIT clocks
+func recordOutput(pit *Simulation.PIT, channel byte, clocks int) []bool {
+	levels := make([]bool, clocks)
+	for i := range levels {
+		tick(pit, 1)
+		levels[i] = pit.Output(channel)
+	}
+	return levels
+}
+
+func expectLevels(t *testing.T, levels []bool, expected string) {
+	t.Helper()
+	actual := make([]byte, len(levels))
+	for i, level := range levels {
+		actual[i] = '_'
+		if level {
+			actual[i] = '-'
+		}
+	}
+	if string(actual) != expected {
+		t.Errorf("output is %s, expected %s", actual, expected)
+	}
+}
+
+func expectCount(t *testing.T, pit *Simulation.PIT, channel byte, expected uint16) {
+	t.Helper()
+	if count := readPIT(pit, channel); count != expected {
+		t.Errorf("count is 0x%04x, expected 0x%04x", count, expected)
+	}
+}
+
+func TestPITClockRatio(t *testing.T) {
+	if Simulation.CPU_CYCLES_PER_PIT_CLOCK != 4 {
+		t.Errorf("%d CPU cycles per PIT clock, expected 4 for 4.77 MHz and 1.193 MHz", Simulation.CPU_CYCLES_PER_PIT_CLOCK)
+	}
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 0, false, 100)
+	pit.Clock(Simulation.CPU_CYCLES_PER_PIT_CLOCK - 1)
+	expectCount(t, pit, 0, 100)
+	pit.Clock(1)
+	expectCount(t, pit, 0, 99)
+	//remaining cycles add up across calls
+	for range 2 * Simulation.CPU_CYCLES_PER_PIT_CLOCK {
+		pit.Clock(1)
+	}
+	expectCount(t, pit, 0, 97)
+}
+
+func TestPITMode0(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 0, false, 5)
+	if pit.Output(0) {
+		t.Error("output is high after loading the count")
+	}
+	expectLevels(t, recordOutput(pit, 0, 7), "____---")
+	//the counter wraps around after the terminal count
+	expectCount(t, pit, 0, 0xFFFE)
+}
+
+func TestPITMode1(t *testing.T) {
+	pit := Simulation.NewPIT()
+	pit.SetGate(1, false)
+	programPIT(pit, 1, 1, false, 3)
+	expectLevels(t, recordOutput(pit, 1, 2), "--")
+	pit.SetGate(1, true)
+	expectLevels(t, recordOutput(pit, 1, 4), "__--")
+	//retrigger
+	pit.SetGate(1, false)
+	pit.SetGate(1, true)
+	expectLevels(t, recordOutput(pit, 1, 4), "__--")
+}
+
+func TestPITMode2(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 2, false, 4)
+	expectLevels(t, recordOutput(pit, 0, 8), "--_---_-")
+	//a new count is used for the next period
+	pit.Out(Simulation.PIT_CHANNEL_0_PORT, 3, false)
+	pit.Out(Simulation.PIT_CHANNEL_0_PORT, 0, false)
+	expectLevels(t, recordOutput(pit, 0, 7), "--_--_-")
+}
+
+func TestPITMode3(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 2, 3, false, 4)
+	expectLevels(t, recordOutput(pit, 2, 8), "-__--__-")
+	//odd counts are high one clock longer
+	programPIT(pit, 2, 3, false, 5)
+	expectLevels(t, recordOutput(pit, 2, 10), "--__---__-")
+}
+
+func TestPITMode4(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 4, false, 3)
+	expectLevels(t, recordOutput(pit, 0, 5), "--_--")
+	//the counter keeps counting from 0xFFFF without another strobe
+	expectCount(t, pit, 0, 0xFFFE)
+	strobes := 0
+	pit.ConnectOutput(0, func(level bool) {
+		if !level {
+			strobes++
+		}
+	})
+	tick(pit, 0x20000)
+	if strobes != 0 {
+		t.Errorf("%d strobes after the counter wrapped around", strobes)
+	}
+}
+
+func TestPITMode5(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 1, 5, false, 2)
+	expectLevels(t, recordOutput(pit, 1, 3), "---")
+	pit.SetGate(1, false)
+	pit.SetGate(1, true)
+	expectLevels(t, recordOutput(pit, 1, 4), "-_--")
+	expectCount(t, pit, 1, 0xFFFE)
+}
+
+func TestPITBCD(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 0, true, 0x0100)
+	tick(pit, 1)
+	expectCount(t, pit, 0, 0x0099)
+	//count 0 is 10000 in BCD
+	programPIT(pit, 0, 0, true, 0)
+	tick(pit, 1)
+	expectCount(t, pit, 0, 0x9999)
+	//BCD count 10 reloads every 10 clocks
+	programPIT(pit, 0, 2, true, 0x0010)
+	expectLevels(t, recordOutput(pit, 0, 20), "--------_---------_-")
+}
+
+func TestPITLatch(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 0, 2, false, 100)
+	tick(pit, 10)
+	pit.Out(Simulation.PIT_CONTROL_PORT, 0, false)
+	tick(pit, 5)
+	//a second latch command is ignored until the latch is read
+	pit.Out(Simulation.PIT_CONTROL_PORT, 0, false)
+	low := pit.In(Simulation.PIT_CHANNEL_0_PORT, false)
+	high := pit.In(Simulation.PIT_CHANNEL_0_PORT, false)
+	if count := low | high<<8; count != 90 {
+		t.Errorf("latched count is %d, expected 90", count)
+	}
+	expectCount(t, pit, 0, 85)
+
+	//LSB only access
+	pit.Out(Simulation.PIT_CONTROL_PORT, 0b00010000, false)
+	pit.Out(Simulation.PIT_CHANNEL_0_PORT, 0x20, false)
+	tick(pit, 1)
+	if count := pit.In(Simulation.PIT_CHANNEL_0_PORT, false); count != 0x1F {
+		t.Errorf("LSB is 0x%02x, expected 0x1f", count)
+	}
+}
+
+func TestPITGate(t *testing.T) {
+	pit := Simulation.NewPIT()
+	programPIT(pit, 2, 2, false, 4)
+	tick(pit, 2)
+	expectCount(t, pit, 2, 2)
+	//a low gate stops counting and forces the output high
+	pit.SetGate(2, false)
+	tick(pit, 5)
+	expectCount(t, pit, 2, 2)
+	if !pit.Output(2) {
+		t.Error("output is low while the gate is low")
+	}
+	//a rising edge reloads the count
+	pit.SetGate(2, true)
+	expectCount(t, pit, 2, 4)
+	expectLevels(t, recordOutput(pit, 2, 4), "--_-")
+}
+
+func TestPITTimerInterrupt(t *testing.T) {
+	cpu := Simulation.NewCPU()
+	pic := cpu.InstallPIC()
+	pit := cpu.InstallPIT(pic)
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x13, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x08, false)
+	pic.Out(Simulation.PIC_DATA_PORT, 0x01, false)
+	programPIT(pit, 0, 2, false, 10)
+	//the output is already high after programming
+	pic.AcknowledgeInterrupt()
+	pic.Out(Simulation.PIC_COMMAND_PORT, 0x20, false)
+	pit.Clock(9 * Simulation.CPU_CYCLES_PER_PIT_CLOCK)
+	if pic.InterruptRequested() {
+		t.Error("timer interrupt requested before the count expired")
+	}
+	pit.Clock(Simulation.CPU_CYCLES_PER_PIT_CLOCK)
+	if !pic.InterruptRequested() {
+		t.Fatal("no timer interrupt requested")
+	}
+	if interruptType := pic.AcknowledgeInterrupt(); interruptType != 0x08 {
+		t.Errorf("timer interrupt type 0x%02x, expected 0x08", interruptType)
+	}
+}
+
+// picProgram initializes the PIC for types 0x08-0x0F with only IRQ 0 unmasked
+const picProgram = "B013E620 B008E621 B001E621 B0FEE621"
+
+func TestHaltWaitsForInterrupt(t *testing.T) {
+	//JMP 100h
+	cpu := newTestCPU(t, "E9FD00")
+	//IRQ 0 handler: INC DX; EOI; IRET
+	poke(t, cpu, 0x20, "00020000")
+	poke(t, cpu, 0x200, "42 B020E620 CF")
+	//PIT channel 0 in mode 2 with count 100, STI, then HLT until DX is 3, CLI; HLT
+	poke(t, cpu, 0x100, picProgram+"B034E643 B064E640 B000E640 FB F4 83FA03 75FA FA F4")
+	cpu.SP = 0x1000
+	cpu.InstallPIT(cpu.InstallPIC())
+	trace := simulate(t, cpu)
+	if cpu.DX != 3 {
+		t.Errorf("DX is %d, expected 3 timer interrupts", cpu.DX)
+	}
+	halts := 0
+	for _, line := range trace {
+		if strings.Contains(line, "; halted") {
+			halts++
+		}
+	}
+	if halts < 2 {
+		t.Errorf("HLT waited %d times, expected at least 2", halts)
+	}
+	if !strings.Contains(trace[len(trace)-1], "HLT") {
+		t.Errorf("simulation did not end at HLT with IF clear: %s", trace[len(trace)-1])
+	}
+}
+
+func TestHaltWithoutInterruptSourceEnds(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+	}{
+		{"uninitialized PIC", ""},
+		//mask all IRQs
+		{"masked PIC", picProgram + "B0FFE621"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			//STI; HLT; INC DX
+			cpu := newTestCPU(t, test.program+"FB F4 42")
+			cpu.InstallPIT(cpu.InstallPIC())
+			simulate(t, cpu)
+			if cpu.DX != 0 {
+				t.Error("execution continued after HLT without an interrupt source")
+			}
+		})
+	}
+}
+
+func TestHaltTimeout(t *testing.T) {
+	//STI; HLT with IRQ 0 unmasked and an unprogrammed PIT
+	cpu := newTestCPU(t, picProgram+"FB F4")
+	cpu.InstallPIT(cpu.InstallPIC())
+	if err := cpu.Simulate(nil); err == nil {
+		t.Error("HLT waited for an interrupt that cannot arrive without failing")
+	}
+}
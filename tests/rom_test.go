@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation"
+)
+
+func TestReadOnlyMemory(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		fails   bool
+	}{
+		//MOV AX, 0xFFFF; MOV DS, AX; instruction with the operand [0x0000] in the reset area; HLT
+		{"MOV", "B8FFFF 8ED8 C606000000 F4", true},
+		{"ADD", "B8FFFF 8ED8 8006000001 F4", true},
+		{"CMP byte immediate", "B8FFFF 8ED8 803E0000EA F4", false},
+		{"CMP word immediate", "B8FFFF 8ED8 813E0000EA00 F4", false},
+		{"CMP register", "B8FFFF 8ED8 39060000 F4", false},
+		{"shift by CL 0", "B8FFFF 8ED8 B100 D3260000 F4", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			cpu.ReadOnlyWrites = Simulation.READ_ONLY_WRITES_FAIL
+			err := cpu.Simulate(nil)
+			var writeError Simulation.MemoryWriteError
+			if test.fails != errors.As(err, &writeError) {
+				t.Errorf("simulation ended with %v", err)
+			}
+			if cpu.Memory[Simulation.RESET_AREA_START] != 0xEA {
+				t.Error("the reset area was changed")
+			}
+		})
+	}
+}
+
+func TestReadOnlyWritesIgnored(t *testing.T) {
+	//MOV AX, 0xFFFF; MOV DS, AX; MOV byte [0x0000], 0; HLT
+	cpu := newTestCPU(t, "B8FFFF 8ED8 C606000000 F4")
+	simulate(t, cpu)
+	if cpu.Memory[Simulation.RESET_AREA_START] != 0xEA {
+		t.Error("the reset area was changed")
+	}
+}
+
+func TestLoadProgramIntoROM(t *testing.T) {
+	//loaders fill ROM, which instructions cannot write
+	cpu := Simulation.NewCPU()
+	cpu.MapROM(0, 0xF)
+	if err := cpu.LoadProgram(decodeHex(t, "B80100 F4"), false); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Memory[0] != 0xB8 || cpu.Memory[3] != 0xF4 {
+		t.Error("the program was not loaded into ROM")
+	}
+}
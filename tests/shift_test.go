@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/P100sch/Intel8086Simulator/Simulation/Disassembly"
+)
+
+func TestShiftFlags(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		ax      uint16
+		cf, of  byte
+	}{
+		//MOV AX, CF or 0; PUSH AX; POPF; MOV AX, a; MOV CL, n; shift AX/AL, CL; HLT
+		{"SHL 2", "B80000 50 9D B80140 B102 D3E0 F4", 0x0004, 1, 1},
+		{"SHL 16", "B80000 50 9D B80100 B110 D3E0 F4", 0x0000, 1, 1},
+		{"SHL 17", "B80000 50 9D B80100 B111 D3E0 F4", 0x0000, 0, 0},
+		{"SHL byte 7", "B80000 50 9D B80300 B107 D2E0 F4", 0x0080, 1, 0},
+		{"SHR 1", "B80000 50 9D B80180 B101 D3E8 F4", 0x4000, 1, 1},
+		{"SHR 2", "B80000 50 9D B80180 B102 D3E8 F4", 0x2000, 0, 0},
+		{"SAR 2", "B80000 50 9D B80380 B102 D3F8 F4", 0xE000, 1, 0},
+		{"ROL 2", "B80000 50 9D B80060 B102 D3C0 F4", 0x8001, 1, 0},
+		{"ROR 2", "B80000 50 9D B80300 B102 D3C8 F4", 0xC000, 1, 0},
+		{"RCL 2", "B80100 50 9D B80080 B102 D3D0 F4", 0x0003, 0, 0},
+		{"RCR 2", "B80100 50 9D B80000 B102 D3D8 F4", 0x4000, 0, 1},
+		{"SAL alias 2", "B80000 50 9D B80140 B102 D3F0 F4", 0x0004, 1, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectFlags(t, "CF", cpu.CF, test.cf)
+			expectFlags(t, "OF", cpu.OF, test.of)
+		})
+	}
+}
+
+func TestShiftByZero(t *testing.T) {
+	for _, operation := range []string{"D3C0", "D3D8", "D3E0", "D3E8", "D3F8"} {
+		t.Run(operation, func(t *testing.T) {
+			//MOV AX, SF|ZF|AF|PF|CF; PUSH AX; POPF; MOV AX, 0x4001; MOV CL, 0; shift AX, CL; HLT
+			cpu := newTestCPU(t, "B8D500 50 9D B80140 B100"+operation+"F4")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, 0x4001)
+			for _, flag := range []struct {
+				name  string
+				value byte
+			}{{"SF", cpu.SF}, {"ZF", cpu.ZF}, {"AF", cpu.AF}, {"PF", cpu.PF}, {"CF", cpu.CF}} {
+				expectFlags(t, flag.name, flag.value, 1)
+			}
+			expectFlags(t, "OF", cpu.OF, 0)
+		})
+	}
+}
+
+func TestSALAlias(t *testing.T) {
+	asm, err := Disassembly.Disassemble(decodeHex(t, "D1F0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(asm, "SAL AX, 1 ;") {
+		t.Errorf("disassembled to %q, expected %q", asm, "SAL AX, 1")
+	}
+
+	//MOV AX, 0xC001; SAL AX, 1 encoded with /6; HLT
+	cpu := newTestCPU(t, "B801C0 D1F0 F4")
+	simulate(t, cpu)
+	expectRegister(t, "AX", cpu.AX, 0x8002)
+	expectFlags(t, "CF", cpu.CF, 1)
+	expectFlags(t, "OF", cpu.OF, 0)
+	expectFlags(t, "SF", cpu.SF, 1)
+}
+
+func TestShiftCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV BX, 0x0200; MOV CL, n; shift; HLT
+		{"register by 1", "BB0002 B105 D1E0 F4", "2"},
+		{"register by CL 0", "BB0002 B100 D3E0 F4", "8"},
+		{"register by CL 5", "BB0002 B105 D3E0 F4", "28"},
+		{"memory by 1", "BB0002 B105 D127 F4", "20"},
+		{"memory by CL 3", "BB0002 B103 D327 F4", "37"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
@@ -47,11 +47,12 @@ func TestSimulate(t *testing.T) {
 		} else {
 			logger.SetOutput(&builder)
 		}
-		err = Simulation.LoadProgram(data, true)
+		cpu := Simulation.NewCPU()
+		err = cpu.LoadProgram(data, true)
 		if err != nil {
 			log.Fatal(err)
 		}
-		err = Simulation.Simulate(&logger)
+		err = cpu.Simulate(&logger)
 		output := builder.String()
 		if err != nil {
 			print(output)
@@ -80,7 +81,6 @@ func TestSimulate(t *testing.T) {
 				fmt.Printf("%"+strconv.FormatFloat(digits, 'f', 0, 64)+"d: %s\n\n", i, outputLines[i])
 			}
 		}
-		Simulation.Rest()
 	}
 }
 
@@ -0,0 +1,89 @@
+package tests
+
+import "testing"
+
+func TestPushSP(t *testing.T) {
+	//MOV SP, 0x1000; PUSH SP; HLT
+	cpu := newTestCPU(t, "BC0010 54 F4")
+	simulate(t, cpu)
+	expectRegister(t, "SP", cpu.SP, 0x0FFE)
+	//the 8086 pushes the decremented SP
+	expectRegister(t, "pushed SP", readWord(cpu, 0, 0x0FFE), 0x0FFE)
+}
+
+func TestPopBasedAddress(t *testing.T) {
+	//MOV AX, 0x0100; MOV SS, AX; MOV SP, 0x0100; MOV AX, 0x1234; PUSH AX; MOV BP, 0x0020; POP [BP+2]; HLT
+	cpu := newTestCPU(t, "B80001 8ED0 BC0001 B83412 50 BD2000 8F4602 F4")
+	simulate(t, cpu)
+	expectRegister(t, "SP", cpu.SP, 0x0100)
+	//BP addresses the stack segment
+	expectRegister(t, "SS:[0x22]", readWord(cpu, 0x0100, 0x22), 0x1234)
+	expectRegister(t, "DS:[0x22]", readWord(cpu, 0, 0x22), 0)
+}
+
+func TestPushFlags(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		flags   uint16
+	}{
+		//MOV AX, a; PUSH AX; POPF; PUSHF; POP BX; HLT
+		{"cleared", "B80000 50 9D 9C 5B F4", 0xF002},
+		{"set without TF and IF", "B8FF0C 50 9D 9C 5B F4", 0xFCD7},
+		{"CF", "B80100 50 9D 9C 5B F4", 0xF003},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			simulate(t, cpu)
+			//the reserved bits 1 and 12 to 15 read as 1, the other reserved bits as 0
+			expectRegister(t, "FLAGS", cpu.BX, test.flags)
+		})
+	}
+}
+
+func TestStackWraps(t *testing.T) {
+	//MOV AX, 0x0100; MOV SS, AX; MOV SP, 0; MOV AX, 0x1234; PUSH AX; HLT
+	cpu := newTestCPU(t, "B80001 8ED0 BC0000 B83412 50 F4")
+	simulate(t, cpu)
+	expectRegister(t, "SP", cpu.SP, 0xFFFE)
+	expectRegister(t, "SS:[0xFFFE]", readWord(cpu, 0x0100, 0xFFFE), 0x1234)
+
+	//MOV AX, 0x0100; MOV SS, AX; MOV SP, 0xFFFF; POP AX; HLT
+	cpu = newTestCPU(t, "B80001 8ED0 BCFFFF 58 F4")
+	poke(t, cpu, 0x10FFF, "34")
+	poke(t, cpu, 0x1000, "12")
+	simulate(t, cpu)
+	expectRegister(t, "SP", cpu.SP, 0x0001)
+	//the high byte is read from the start of the stack segment
+	expectRegister(t, "AX", cpu.AX, 0x1234)
+}
+
+func TestStackCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV SP, s; MOV BX, 0x0200; stack instruction; HLT
+		{"PUSH register", "BC0010 BB0002 50 F4", "11"},
+		{"PUSH register odd", "BC0110 BB0002 50 F4", "15"},
+		{"POP register", "BC0010 BB0002 58 F4", "8"},
+		{"PUSH segment", "BC0010 BB0002 1E F4", "10"},
+		{"POP segment", "BC0010 BB0002 1F F4", "8"},
+		{"PUSHF", "BC0010 BB0002 9C F4", "10"},
+		{"POPF", "BC0010 BB0002 9D F4", "8"},
+		{"PUSH memory", "BC0010 BB0002 FF37 F4", "21"},
+		{"POP memory", "BC0010 BB0002 8F07 F4", "22"},
+		{"POP memory odd", "BC0110 BB0102 8F07 F4", "30"},
+		{"POP register with ModRM", "BC0010 BB0002 8FC0 F4", "8"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
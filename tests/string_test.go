@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRepeatedCompare(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		program    string
+		cx, si, di uint16
+		zf         byte
+	}{
+		//MOV SI, 0x200; MOV DI, 0x300; MOV CX, n; [MOV AL, a]; REPE/REPNE CMPS/SCAS; HLT
+		{"REPE CMPSB mismatch", "BE0002 BF0003 B90400 F3A6 F4", 1, 0x203, 0x303, 0},
+		{"REPNE CMPSB match", "BE0002 BF0003 B90400 F2A6 F4", 3, 0x201, 0x301, 1},
+		{"REPE CMPSW exhausted", "BE0002 BF0003 B90200 F3A7 F4", 0, 0x204, 0x304, 0},
+		{"REPNE SCASB match", "BE0002 BF0003 B90400 B058 F2AE F4", 1, 0x200, 0x303, 1},
+		{"REPNE SCASB exhausted", "BE0002 BF0003 B90400 B000 F2AE F4", 0, 0x200, 0x304, 0},
+		{"REPE SCASB mismatch", "BE0002 BF0003 B90400 B041 F3AE F4", 2, 0x200, 0x302, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x200, "41424344")
+			poke(t, cpu, 0x300, "41425844")
+			simulate(t, cpu)
+			expectRegister(t, "CX", cpu.CX, test.cx)
+			expectRegister(t, "SI", cpu.SI, test.si)
+			expectRegister(t, "DI", cpu.DI, test.di)
+			expectFlags(t, "ZF", cpu.ZF, test.zf)
+		})
+	}
+}
+
+func TestRepeatedMoveBackwards(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		si, di  uint16
+	}{
+		//MOV AX, DF; PUSH AX; POPF; MOV SI, s; MOV DI, d; MOV CX, n; REP MOVSB/MOVSW; HLT
+		{"MOVSB", "B80004 50 9D BE0302 BF0303 B90400 F3A4 F4", 0x1FF, 0x2FF},
+		{"MOVSW", "B80004 50 9D BE0202 BF0203 B90200 F3A5 F4", 0x1FE, 0x2FE},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x200, "41424344")
+			simulate(t, cpu)
+			expectRegister(t, "CX", cpu.CX, 0)
+			expectRegister(t, "SI", cpu.SI, test.si)
+			expectRegister(t, "DI", cpu.DI, test.di)
+			if copied := cpu.Memory[0x300:0x304]; !bytes.Equal(copied, []byte("ABCD")) {
+				t.Errorf("copied %q, expected %q", copied, "ABCD")
+			}
+		})
+	}
+}
+
+func TestRepeatedSourceOverride(t *testing.T) {
+	for _, prefixes := range []string{"26F3", "F326"} {
+		t.Run(prefixes, func(t *testing.T) {
+			//MOV AX, 0x0020; MOV ES, AX; MOV SI, 0; MOV DI, 0x100; MOV CX, 4; ES REP MOVSB in both orders; HLT
+			cpu := newTestCPU(t, "B82000 8EC0 BE0000 BF0001 B90400"+prefixes+"A4 F4")
+			poke(t, cpu, 0x200, "41424344")
+			simulate(t, cpu)
+			//ES:SI instead of DS:SI, the destination is always ES:DI
+			if copied := cpu.Memory[0x300:0x304]; !bytes.Equal(copied, []byte("ABCD")) {
+				t.Errorf("copied %q, expected %q", copied, "ABCD")
+			}
+		})
+	}
+}
+
+func TestRepeatCycles(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mnemonic string
+		program  string
+		cycles   []string
+	}{
+		//MOV SI, s; MOV DI, 0x300; MOV CX, n; string instruction; HLT
+		{"REP MOVSB", "MOVSB", "BE0002 BF0003 B90300 F3A4 F4", []string{"26", "17", "17"}},
+		{"REP MOVSB with CX 0", "MOVSB", "BE0002 BF0003 B90000 F3A4 F4", []string{"9"}},
+		{"ES REP MOVSB", "MOVSB", "BE0002 BF0003 B90200 26F3A4 F4", []string{"28", "17"}},
+		{"REP MOVSW odd source", "MOVSW", "BE0102 BF0003 B90200 F3A5 F4", []string{"30", "21"}},
+		{"REPE CMPSB", "CMPSB", "BE0003 BF0003 B90200 F3A6 F4", []string{"31", "22"}},
+		{"REPNE SCASB", "SCASB", "BE0002 BF0003 B90200 F2AE F4", []string{"24", "15"}},
+		{"REP LODSB", "LODSB", "BE0002 BF0003 B90200 F3AC F4", []string{"22", "13"}},
+		{"REP STOSB", "STOSB", "BE0002 BF0003 B90200 F3AA F4", []string{"19", "10"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x300, "41424344")
+			var cycles []string
+			for _, line := range simulate(t, cpu) {
+				if strings.Contains(line, test.mnemonic) {
+					cycles = append(cycles, clocks(t, line))
+				}
+			}
+			if strings.Join(cycles, " ") != strings.Join(test.cycles, " ") {
+				t.Errorf("iterations took %v cycles, expected %v", cycles, test.cycles)
+			}
+		})
+	}
+}
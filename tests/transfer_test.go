@@ -0,0 +1,93 @@
+package tests
+
+import "testing"
+
+func TestDataTransfer(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		program           string
+		ax, bx, dx, di    uint16
+		ds, es            uint16
+		memory, memoryVal uint16
+	}{
+		//MOV AX, 1; MOV BX, 2; XCHG AX, BX; HLT
+		{"XCHG accumulator", "B80100 BB0200 93 F4", 2, 1, 0, 0, 0, 0, 0x200, 0x2211},
+		//MOV BX, 0x0200; MOV CX, 0x5566; XCHG [BX], CX; MOV AX, CX; HLT
+		{"XCHG memory", "BB0002 B96655 870F 89C8 F4", 0x2211, 0x200, 0, 0, 0, 0, 0x200, 0x5566},
+		//MOV BX, 0x0200; MOV SI, 3; LEA AX, [BX+SI+5]; HLT
+		{"LEA", "BB0002 BE0300 8D4005 F4", 0x208, 0x200, 0, 0, 0, 0, 0x200, 0x2211},
+		//MOV BP, 0x10; LEA AX, ES:[BP+2]; HLT
+		{"LEA ignores segments", "BD1000 268D4602 F4", 0x12, 0, 0, 0, 0, 0, 0x200, 0x2211},
+		//MOV BX, 0x0200; LDS BX, [BX]; HLT
+		{"LDS pointer register", "BB0002 C51F F4", 0, 0x2211, 0, 0, 0x4433, 0, 0x200, 0x2211},
+		//LES DI, [0x202]; HLT
+		{"LES", "C43E0202 F4", 0, 0, 0, 0x4433, 0, 0x6655, 0x200, 0x2211},
+		//MOV BX, 0x0200; MOV AL, 2; XLAT; HLT
+		{"XLAT", "BB0002 B002 D7 F4", 0x0033, 0x200, 0, 0, 0, 0, 0x200, 0x2211},
+		//MOV AX, 0x0020; MOV ES, AX; MOV BX, 0; MOV AL, 3; ES XLAT; HLT
+		{"XLAT segment override", "B82000 8EC0 BB0000 B003 26D7 F4", 0x0044, 0, 0, 0, 0, 0x20, 0x200, 0x2211},
+		//MOV AX, a; CBW/CWD; HLT
+		{"CBW negative", "B88000 98 F4", 0xFF80, 0, 0, 0, 0, 0, 0x200, 0x2211},
+		{"CBW positive", "B87FFF 98 F4", 0x007F, 0, 0, 0, 0, 0, 0x200, 0x2211},
+		{"CWD negative", "B80080 99 F4", 0x8000, 0, 0xFFFF, 0, 0, 0, 0x200, 0x2211},
+		{"CWD positive", "B8FF7F BAFFFF 99 F4", 0x7FFF, 0, 0, 0, 0, 0, 0x200, 0x2211},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU(t, test.program)
+			poke(t, cpu, 0x200, "1122334455667788")
+			simulate(t, cpu)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "BX", cpu.BX, test.bx)
+			expectRegister(t, "DX", cpu.DX, test.dx)
+			expectRegister(t, "DI", cpu.DI, test.di)
+			expectRegister(t, "DS", cpu.DS, test.ds)
+			expectRegister(t, "ES", cpu.ES, test.es)
+			expectRegister(t, "[0x200]", readWord(cpu, 0, test.memory), test.memoryVal)
+		})
+	}
+}
+
+func TestFlagsThroughAH(t *testing.T) {
+	//MOV AX, OF|SF|ZF|AF|PF|CF; PUSH AX; POPF; LAHF; HLT
+	cpu := newTestCPU(t, "B8D508 50 9D 9F F4")
+	simulate(t, cpu)
+	//bit 1 reads as 1, bits 3 and 5 as 0
+	expectRegister(t, "AX", cpu.AX, 0xD7D5)
+
+	//MOV AX, OF; PUSH AX; POPF; MOV AH, 0xFF; SAHF; PUSHF; POP BX; HLT
+	cpu = newTestCPU(t, "B80008 50 9D B4FF 9E 9C 5B F4")
+	simulate(t, cpu)
+	//SAHF keeps OF
+	expectRegister(t, "FLAGS", cpu.BX, 0xF8D7)
+}
+
+func TestDataTransferCycles(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		program string
+		cycles  string
+	}{
+		//MOV BX, 0x0200; operation; HLT
+		{"XCHG accumulator", "BB0002 93 F4", "3"},
+		{"XCHG register", "BB0002 87CA F4", "4"},
+		{"XCHG memory", "BB0002 870F F4", "22"},
+		{"LEA", "BB0002 8D4705 F4", "11"},
+		{"LEA odd", "BB0102 8D07 F4", "7"},
+		{"LDS", "BB0002 C537 F4", "21"},
+		{"LES odd", "BB0102 C437 F4", "29"},
+		{"XLAT", "BB0002 D7 F4", "11"},
+		{"CBW", "BB0002 98 F4", "2"},
+		{"CWD", "BB0002 99 F4", "5"},
+		{"LAHF", "BB0002 9F F4", "4"},
+		{"SAHF", "BB0002 9E F4", "4"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := simulate(t, newTestCPU(t, test.program))
+			//the instruction before HLT
+			line := trace[len(trace)-2]
+			if actual := clocks(t, line); actual != test.cycles {
+				t.Errorf("%s took %s cycles, expected %s", line, actual, test.cycles)
+			}
+		})
+	}
+}
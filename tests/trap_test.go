@@ -0,0 +1,53 @@
+package tests
+
+import "testing"
+
+func TestTrap(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		program  string
+		returnIP uint16
+		ax       uint16
+	}{
+		//MOV AX, TF; PUSH AX; POPF; INC AX; HLT
+		{"POPF", "B80001 50 9D 40 F4", 0x106, 0x101},
+		//MOV AX, TF; PUSH AX; PUSH CS; MOV AX, 0x010A; PUSH AX; IRET; INC AX; HLT
+		{"IRET", "B80001 50 0E B80A01 50 CF 40 F4", 0x10B, 0x10B},
+		//MOV AX, TF; PUSH AX; POPF; MOV SS, BX; INC AX; HLT
+		{"MOV SS", "B80001 50 9D 8ED3 40 F4", 0x108, 0x101},
+		//PUSH SS; MOV AX, TF; PUSH AX; POPF; POP SS; INC AX; HLT
+		{"POP SS", "16 B80001 50 9D 17 40 F4", 0x108, 0x101},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newInterruptTestCPU(t, test.program)
+			simulate(t, cpu)
+			//the instruction after the one that sets TF and after a load of SS runs before the trap
+			expectInterrupt(t, cpu, 1, test.returnIP)
+			expectRegister(t, "AX", cpu.AX, test.ax)
+			expectRegister(t, "pushed FLAGS", readWord(cpu, cpu.SS, cpu.SP+4), 0xF102)
+			expectFlags(t, "TF", cpu.TF, 0)
+		})
+	}
+}
+
+func TestTrapAfterInterrupt(t *testing.T) {
+	//MOV AX, TF|IF; PUSH AX; POPF; INT 3; HLT
+	cpu := newInterruptTestCPU(t, "B80003 50 9D CC F4")
+	simulate(t, cpu)
+	//INT 3 clears TF and IF, the trap is taken before the first instruction of its handler
+	expectInterrupt(t, cpu, 1, 0x203)
+	expectRegister(t, "pushed FLAGS", readWord(cpu, cpu.SS, cpu.SP+4), 0xF002)
+	expectRegister(t, "INT 3 IP", readWord(cpu, cpu.SS, cpu.SP+6), 0x106)
+	expectRegister(t, "INT 3 FLAGS", readWord(cpu, cpu.SS, cpu.SP+10), 0xF302)
+	expectFlags(t, "IF", cpu.IF, 0)
+	expectFlags(t, "TF", cpu.TF, 0)
+}
+
+func TestRepeatedTrapResumesAtLastPrefix(t *testing.T) {
+	//MOV CX, 3; MOV SI, 0x300; MOV DI, 0x400; MOV AX, TF; PUSH AX; POPF; ES REP MOVSB; HLT
+	cpu := newInterruptTestCPU(t, "B90300 BE0003 BF0004 B80001 50 9D 26F3A4 F4")
+	simulate(t, cpu)
+	//the ES prefix at 0x10E is lost, the iteration is resumed at REP
+	expectInterrupt(t, cpu, 1, 0x10F)
+	expectRegister(t, "CX", cpu.CX, 2)
+}